@@ -0,0 +1,62 @@
+package flexihash
+
+// LookupMany finds the target for each of keys. The result has one target
+// per key, in the same order as keys.
+func (fh *FlexiHash) LookupMany(keys []string) ([]string, error) {
+	targets := make([]string, len(keys))
+	for i, key := range keys {
+		target, err := fh.Lookup(key)
+		if err != nil {
+			return nil, err
+		}
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+// LookupGrouped finds the target for each of keys and groups the keys by
+// target. Each group keeps its keys in the order they appear in keys.
+func (fh *FlexiHash) LookupGrouped(keys []string) (map[string][]string, error) {
+	groups := make(map[string][]string, min(len(keys), fh.targetCount))
+	for _, key := range keys {
+		target, err := fh.Lookup(key)
+		if err != nil {
+			return nil, err
+		}
+		groups[target] = append(groups[target], key)
+	}
+	return groups, nil
+}
+
+// LookupMany finds the target for each of keys, in the same order as keys.
+// All keys are looked up on one BatchSnapshot, so membership and status
+// changes made during the call do not split the batch.
+func (c *ConcurrentFlexiHash) LookupMany(keys []string) ([]string, error) {
+	return c.BatchSnapshot().LookupMany(keys)
+}
+
+// LookupGrouped finds the target for each of keys and groups the keys by
+// target, using one BatchSnapshot for the whole batch
+func (c *ConcurrentFlexiHash) LookupGrouped(keys []string) (map[string][]string, error) {
+	return c.BatchSnapshot().LookupGrouped(keys)
+}
+
+// BatchSnapshot returns the current snapshot with the target statuses
+// fixed as well, so a batch of lookups on it sees neither membership nor
+// status changes. It copies the statuses of targets that are not up, so
+// use Snapshot for single lookups.
+func (c *ConcurrentFlexiHash) BatchSnapshot() *FlexiHash {
+	// Writers publish and update statuses under mu, so holding it keeps
+	// the ring and the statuses consistent with each other
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view := *c.Snapshot()
+	view.sharedStatus = nil
+	view.targetStatus = make(map[string]TargetStatus)
+	c.statuses.Range(func(target, status any) bool {
+		view.targetStatus[target.(string)] = status.(TargetStatus)
+		return true
+	})
+	return &view
+}
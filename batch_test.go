@@ -0,0 +1,134 @@
+package flexihash
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestLookupMany(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	targets, err := fh.LookupMany(keys)
+	if err != nil {
+		t.Fatalf("LookupMany failed: %v", err)
+	}
+	if len(targets) != len(keys) {
+		t.Fatalf("Expected %d targets, got %d", len(keys), len(targets))
+	}
+	for i, key := range keys {
+		expected, _ := fh.Lookup(key)
+		if targets[i] != expected {
+			t.Errorf("Key %s: expected %s, got %s", key, expected, targets[i])
+		}
+	}
+
+	if targets, err := fh.LookupMany(nil); err != nil || len(targets) != 0 {
+		t.Errorf("Expected no targets for no keys, got %v, %v", targets, err)
+	}
+	if _, err := NewFlexiHash().LookupMany(keys); err == nil {
+		t.Error("Expected error when no targets exist")
+	}
+}
+
+func TestLookupGrouped(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	groups, err := fh.LookupGrouped(keys)
+	if err != nil {
+		t.Fatalf("LookupGrouped failed: %v", err)
+	}
+
+	// Flattening the groups in key order must give back the keys
+	next := make(map[string]int)
+	for _, key := range keys {
+		target, _ := fh.Lookup(key)
+		group := groups[target]
+		if next[target] >= len(group) || group[next[target]] != key {
+			t.Fatalf("Key %s: expected next in group %s, got %v", key, target, group)
+		}
+		next[target]++
+	}
+	for target, group := range groups {
+		if next[target] != len(group) {
+			t.Errorf("Group %s: expected %d keys, got %d", target, next[target], len(group))
+		}
+	}
+
+	if _, err := NewFlexiHash().LookupGrouped(keys); err == nil {
+		t.Error("Expected error when no targets exist")
+	}
+}
+
+// hookHasher calls hook the first time it hashes "trigger", to change the
+// ring in the middle of a batch
+type hookHasher struct {
+	hook func()
+}
+
+func (h *hookHasher) Hash(str string) int {
+	if str == "trigger" && h.hook != nil {
+		hook := h.hook
+		h.hook = nil
+		hook()
+	}
+	return (&Crc32Hasher{}).Hash(str)
+}
+
+func TestConcurrentBatchIgnoresChangesDuringBatch(t *testing.T) {
+	keys := make([]string, 200)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	keys[100] = "trigger"
+	expected := NewFlexiHash()
+	expected.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	want, _ := expected.LookupMany(keys)
+
+	hasher := &hookHasher{}
+	cfh := NewConcurrentFlexiHashWithHasher(hasher, 0)
+	cfh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	change := func() {
+		cfh.SetTargetStatus("t1", StatusDown)
+		cfh.AddTarget("t4", 1)
+	}
+
+	hasher.hook = change
+	got, err := cfh.LookupMany(keys)
+	if err != nil {
+		t.Fatalf("LookupMany failed: %v", err)
+	}
+	for i, key := range keys {
+		if got[i] != want[i] {
+			t.Errorf("Key %s: expected %s from the ring at the start of the batch, got %s", key, want[i], got[i])
+		}
+	}
+
+	cfh.SetTargetStatus("t1", StatusUp)
+	cfh.RemoveTarget("t4")
+	hasher.hook = change
+	groups, err := cfh.LookupGrouped(keys)
+	if err != nil {
+		t.Fatalf("LookupGrouped failed: %v", err)
+	}
+	for i, key := range keys {
+		if !slices.Contains(groups[want[i]], key) {
+			t.Errorf("Key %s: expected in group %s, got %v", key, want[i], groups)
+		}
+	}
+
+	// Without a batch, lookups after the change see it
+	if targets, _ := cfh.LookupMany(keys); slices.Contains(targets, "t1") || !slices.Contains(targets, "t4") {
+		t.Errorf("Expected later batches to skip t1 and use t4, got %v", targets)
+	}
+}
@@ -0,0 +1,200 @@
+package flexihash
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// defaultLoadFactor is the load factor used when none is given
+const defaultLoadFactor = 1.25
+
+// BoundedFlexiHash implements consistent hashing with bounded loads
+// (Mirrokni, Thorup and Zadimoghaddam).
+//
+// Callers report in-flight load per target, and no target is given more
+// than ceil(c × average load), where c is the load factor: lookups walk
+// clockwise past targets that are at capacity. Capacity does not take
+// target weights into account. BoundedFlexiHash is safe for concurrent use.
+type BoundedFlexiHash struct {
+	ring       *ConcurrentFlexiHash
+	loadFactor float64
+
+	mu        sync.Mutex // guards loads and totalLoad
+	loads     map[string]int
+	totalLoad int
+}
+
+var _ Ring = (*BoundedFlexiHash)(nil)
+
+// NewBoundedFlexiHash creates a BoundedFlexiHash with default hasher and replicas
+func NewBoundedFlexiHash(loadFactor float64) *BoundedFlexiHash {
+	return NewBoundedFlexiHashWithHasher(nil, 0, loadFactor)
+}
+
+// NewBoundedFlexiHashWithHasher creates a BoundedFlexiHash with custom hasher and replicas.
+// The load factor must be greater than 1; 0 uses 1.25.
+func NewBoundedFlexiHashWithHasher(hasher Hasher, replicas int, loadFactor float64) *BoundedFlexiHash {
+	if loadFactor <= 1 {
+		loadFactor = defaultLoadFactor
+	}
+	return &BoundedFlexiHash{
+		ring:       NewConcurrentFlexiHashWithHasher(hasher, replicas),
+		loadFactor: loadFactor,
+		loads:      make(map[string]int),
+	}
+}
+
+// AddTarget adds a target to the hash ring with optional weight
+func (b *BoundedFlexiHash) AddTarget(target string, weight float64) error {
+	return b.ring.AddTarget(target, weight)
+}
+
+// AddTargets adds multiple targets with optional weight
+func (b *BoundedFlexiHash) AddTargets(targets []string, weight float64) error {
+	return b.ring.AddTargets(targets, weight)
+}
+
+// RemoveTarget removes a target from the hash ring and discards its load
+func (b *BoundedFlexiHash) RemoveTarget(target string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.ring.RemoveTarget(target); err != nil {
+		return err
+	}
+	b.totalLoad -= b.loads[target]
+	delete(b.loads, target)
+	return nil
+}
+
+// GetAllTargets returns a list of all potential targets
+func (b *BoundedFlexiHash) GetAllTargets() []string {
+	return b.ring.GetAllTargets()
+}
+
+// Lookup finds the first target clockwise from the resource that has
+// capacity for one more unit of load, without acquiring it
+func (b *BoundedFlexiHash) Lookup(resource string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lookup(resource)
+}
+
+// LookupList returns targets with spare capacity for the resource, in order of precedence
+func (b *BoundedFlexiHash) LookupList(resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return nil, errors.New("Invalid count requested")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fh := b.ring.Snapshot()
+	result := []string{}
+	if fh.targetCount == 0 {
+		return result, nil
+	}
+
+	capacity := b.capacity(fh)
+	fh.walk(fh.lookupPosition(resource), func(target string) bool {
+		if b.loads[target] < capacity {
+			result = append(result, target)
+		}
+		return len(result) < requestedCount
+	})
+	return result, nil
+}
+
+// Acquire finds the target for a resource like Lookup and adds one unit
+// of load to it. Each successful Acquire must be paired with a Release.
+func (b *BoundedFlexiHash) Acquire(resource string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	target, err := b.lookup(resource)
+	if err != nil {
+		return "", err
+	}
+	b.loads[target]++
+	b.totalLoad++
+	return target, nil
+}
+
+// Release removes one unit of load from a target.
+// Releasing a target that has since been removed is a no-op.
+func (b *BoundedFlexiHash) Release(target string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.ring.Snapshot().targetToPositions[target]; !exists {
+		return nil
+	}
+	if b.loads[target] == 0 {
+		return errors.New("Target '" + target + "' has no load to release")
+	}
+	b.loads[target]--
+	b.totalLoad--
+	return nil
+}
+
+// SetLoad replaces the in-flight load reported for a target
+func (b *BoundedFlexiHash) SetLoad(target string, load int) error {
+	if load < 0 {
+		return errors.New("Invalid load for target '" + target + "'")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.ring.Snapshot().targetToPositions[target]; !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+	b.totalLoad += load - b.loads[target]
+	b.loads[target] = load
+	return nil
+}
+
+// Load returns the in-flight load of a target
+func (b *BoundedFlexiHash) Load(target string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.loads[target]
+}
+
+// lookup returns the first target with spare capacity.
+// If every target is at capacity, the unbounded choice is returned.
+// The caller must hold b.mu.
+func (b *BoundedFlexiHash) lookup(resource string) (string, error) {
+	fh := b.ring.Snapshot()
+	if fh.targetCount == 0 {
+		return "", errors.New("No targets exist")
+	}
+
+	capacity := b.capacity(fh)
+	var first, found string
+	fh.walk(fh.lookupPosition(resource), func(target string) bool {
+		if first == "" {
+			first = target
+		}
+		if b.loads[target] < capacity {
+			found = target
+			return false
+		}
+		return true
+	})
+	if first == "" {
+		return "", errors.New("No targets exist")
+	}
+	if found == "" {
+		return first, nil
+	}
+	return found, nil
+}
+
+// capacity returns the maximum load per target, counting the load about
+// to be placed. The caller must hold b.mu.
+func (b *BoundedFlexiHash) capacity(fh *FlexiHash) int {
+	average := float64(b.totalLoad+1) / float64(fh.targetCount)
+	return int(math.Ceil(b.loadFactor * average))
+}
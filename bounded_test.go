@@ -0,0 +1,134 @@
+package flexihash
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBoundedFlexiHashMatchesRingWhenIdle(t *testing.T) {
+	fh := NewFlexiHash()
+	b := NewBoundedFlexiHash(1.25)
+	targets := []string{"cache-1", "cache-2", "cache-3"}
+	fh.AddTargets(targets, 1)
+	b.AddTargets(targets, 1)
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		expected, _ := fh.Lookup(key)
+		if got, _ := b.Lookup(key); got != expected {
+			t.Errorf("Key %s: expected %s, got %s", key, expected, got)
+		}
+	}
+}
+
+func TestBoundedFlexiHashSkipsFullTargets(t *testing.T) {
+	mockHasher := &MockHasher{}
+	b := NewBoundedFlexiHashWithHasher(mockHasher, 1, 1.5)
+
+	mockHasher.hashValue = 10
+	b.AddTarget("t1", 1)
+	mockHasher.hashValue = 20
+	b.AddTarget("t2", 1)
+	mockHasher.hashValue = 30
+	b.AddTarget("t3", 1)
+
+	// Every key hashes next to t1, so load spills clockwise once
+	// t1 reaches ceil(1.5 × average)
+	mockHasher.hashValue = 5
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		target, err := b.Acquire("resource")
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		counts[target]++
+	}
+
+	for target, count := range counts {
+		if count > 15 {
+			t.Errorf("Target %s got %d of 30 loads, above the 1.5 × average bound", target, count)
+		}
+		if b.Load(target) != count {
+			t.Errorf("Target %s: expected load %d, got %d", target, count, b.Load(target))
+		}
+	}
+	if counts["t1"] == 0 || counts["t2"] == 0 {
+		t.Errorf("Expected load on t1 and t2, got %v", counts)
+	}
+
+	b.SetLoad("t1", 100)
+	targets, _ := b.LookupList("resource", 3)
+	for _, target := range targets {
+		if target == "t1" {
+			t.Errorf("Full target t1 returned by LookupList: %v", targets)
+		}
+	}
+}
+
+func TestBoundedFlexiHashReleaseAndSetLoad(t *testing.T) {
+	b := NewBoundedFlexiHash(0)
+	b.AddTargets([]string{"t1", "t2"}, 1)
+
+	if err := b.Release("t2"); err == nil {
+		t.Error("Expected error when releasing a target never acquired")
+	}
+	target, _ := b.Acquire("resource")
+	if err := b.Release(target); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+	if err := b.Release(target); err == nil {
+		t.Error("Expected error when releasing a target with no load")
+	}
+
+	if err := b.SetLoad("t1", 7); err != nil {
+		t.Errorf("SetLoad failed: %v", err)
+	}
+	if err := b.SetLoad("missing", 1); err == nil {
+		t.Error("Expected error when setting load of non-existent target")
+	}
+	if err := b.SetLoad("t1", -1); err == nil {
+		t.Error("Expected error for negative load")
+	}
+
+	b.RemoveTarget("t1")
+	if b.Load("t1") != 0 {
+		t.Errorf("Expected load of removed target to be discarded, got %d", b.Load("t1"))
+	}
+	if err := b.Release("t1"); err != nil {
+		t.Errorf("Releasing a removed target should be a no-op, got %v", err)
+	}
+}
+
+func TestBoundedFlexiHashParallelAcquire(t *testing.T) {
+	b := NewBoundedFlexiHash(1.25)
+	for i := 0; i < 5; i++ {
+		b.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				target, err := b.Acquire("key-" + strconv.Itoa(g) + "-" + strconv.Itoa(i))
+				if err != nil {
+					t.Errorf("Acquire failed: %v", err)
+					return
+				}
+				if err := b.Release(target); err != nil {
+					t.Errorf("Release failed: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, target := range b.GetAllTargets() {
+		if b.Load(target) != 0 {
+			t.Errorf("Target %s: expected load 0 after all releases, got %d", target, b.Load(target))
+		}
+	}
+}
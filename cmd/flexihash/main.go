@@ -0,0 +1,287 @@
+// Command flexihash answers placement questions about a FlexiHash ring.
+//
+// Usage:
+//
+//	flexihash lookup  [ring flags] [key ...]
+//	flexihash list    [ring flags] [-n count] [-distinct] [key ...]
+//	flexihash stats   [ring flags]
+//	flexihash diff    [-arcs] FROM_RING TO_RING
+//	flexihash vectors [ring flags] [key ...]
+//	flexihash serve   [ring flags] [-addr host:port]
+//
+// The ring is read from a JSON or YAML file given with -ring, or built from
+// -targets, -hasher and -replicas. Keys are read from the arguments, or one
+// per line from standard input when there are none.
+//
+// serve answers lookups over HTTP using package flexihttp. Its admin
+// endpoints are enabled by setting FLEXIHASH_ADMIN_TOKEN.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flexihash "github.com/mysamimi/flexiHash"
+)
+
+const usage = `Usage: flexihash <command> [flags] [args]
+
+Commands:
+  lookup   print the target for each key
+  list     print the n targets for each key, in order of precedence
+  stats    print each target's share of the hash space
+  diff     print keyspace movement between two ring files
+  vectors  print a PHP script asserting the lookups of each key
+  serve    answer lookups over HTTP
+
+Run "flexihash <command> -h" for the flags of a command.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "flexihash:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run executes a command, reading keys from stdin when needed
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errors.New("missing command")
+	}
+
+	commands := map[string]func([]string, io.Reader, io.Writer, io.Writer) error{
+		"lookup":  runLookup,
+		"list":    runList,
+		"stats":   runStats,
+		"diff":    runDiff,
+		"vectors": runVectors,
+		"serve":   runServe,
+	}
+	command, exists := commands[args[0]]
+	if !exists {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return command(args[1:], stdin, stdout, stderr)
+}
+
+func runLookup(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("lookup", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ringFlags := addRingFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ring, err := ringFlags.load()
+	if err != nil {
+		return err
+	}
+
+	return forEachKey(flags.Args(), stdin, func(key string) error {
+		target, err := ring.Lookup(key)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\t%s\n", key, target)
+		return err
+	})
+}
+
+func runList(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ringFlags := addRingFlags(flags)
+	count := flags.Int("n", 2, "number of targets per key")
+	distinct := flags.Bool("distinct", false, "place targets in distinct zones where possible")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ring, err := ringFlags.load()
+	if err != nil {
+		return err
+	}
+
+	lookupList := ring.LookupList
+	if *distinct {
+		lookupList = ring.LookupListDistinct
+	}
+	return forEachKey(flags.Args(), stdin, func(key string) error {
+		targets, err := lookupList(key, *count)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\t%s\n", key, strings.Join(targets, ","))
+		return err
+	})
+}
+
+func runStats(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ringFlags := addRingFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ring, err := ringFlags.load()
+	if err != nil {
+		return err
+	}
+
+	stats, err := ring.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%-24s %8s %9s %8s %8s %6s\n", "TARGET", "WEIGHT", "POSITIONS", "SHARE", "EXPECTED", "RATIO")
+	for _, t := range stats.Targets {
+		fmt.Fprintf(stdout, "%-24s %8g %9d %7.3f%% %7.3f%% %6.3f\n",
+			t.Target, t.Weight, t.Positions, t.Share*100, t.ExpectedShare*100, t.Ratio)
+	}
+	fmt.Fprintf(stdout, "\nstddev %.4f, peak-to-mean %.4f, collisions %d\n", stats.StdDev, stats.PeakToMean, ring.Collisions())
+	if fingerprint, err := ring.Fingerprint(); err == nil {
+		fmt.Fprintf(stdout, "fingerprint %s\n", fingerprint)
+	}
+	return nil
+}
+
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	arcs := flags.Bool("arcs", false, "print every arc that changes owner")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("diff needs two ring files")
+	}
+
+	from, err := loadRingFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := loadRingFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	diff, err := flexihash.Diff(from, to)
+	if err != nil {
+		return err
+	}
+
+	if *arcs {
+		for _, move := range diff.Moves {
+			fmt.Fprintf(stdout, "[%d, %d)\t%s -> %s\n", move.Arc.Start, move.Arc.End, describe(move.From), describe(move.To))
+		}
+		fmt.Fprintln(stdout)
+	}
+	for _, transfer := range diff.Transfers() {
+		fmt.Fprintf(stdout, "%s -> %s\t%.3f%%\n", describe(transfer.From), describe(transfer.To), transfer.Fraction*100)
+	}
+	fmt.Fprintf(stdout, "total\t%.3f%%\n", diff.Fraction()*100)
+	return nil
+}
+
+func runVectors(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("vectors", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ringFlags := addRingFlags(flags)
+	count := flags.Int("n", 2, "number of targets per key for lookupList assertions")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ring, err := ringFlags.load()
+	if err != nil {
+		return err
+	}
+	def, err := ring.Definition()
+	if err != nil {
+		return err
+	}
+
+	if def.Probes > 1 || def.Ketama {
+		return errors.New("vectors does not support multi-probe or ketama rings, which PHP flexihash lacks")
+	}
+	phpHashers := map[string]string{"crc32": "Crc32Hasher", "md5": "Md5Hasher"}
+	phpHasher, supported := phpHashers[def.Hasher]
+	if !supported {
+		return errors.New("vectors requires the crc32 or md5 hasher, as PHP flexihash has no others")
+	}
+
+	fmt.Fprintln(stdout, "<?php")
+	fmt.Fprintln(stdout, "require_once 'vendor/autoload.php';")
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "use Flexihash\\Flexihash;")
+	fmt.Fprintf(stdout, "use Flexihash\\Hasher\\%s;\n", phpHasher)
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "$hash = new Flexihash(new %s(), %d);\n", phpHasher, def.Replicas)
+	for _, target := range def.Targets {
+		fmt.Fprintf(stdout, "$hash->addTarget(%s, %g);\n", phpString(target.Name), target.Weight)
+	}
+	fmt.Fprintln(stdout)
+
+	err = forEachKey(flags.Args(), stdin, func(key string) error {
+		target, err := ring.Lookup(key)
+		if err != nil {
+			return err
+		}
+		targets, err := ring.LookupList(key, *count)
+		if err != nil {
+			return err
+		}
+		expected := make([]string, len(targets))
+		for i, t := range targets {
+			expected[i] = phpString(t)
+		}
+		fmt.Fprintf(stdout, "assert($hash->lookup(%s) === %s);\n", phpString(key), phpString(target))
+		fmt.Fprintf(stdout, "assert($hash->lookupList(%s, %d) === [%s]);\n", phpString(key), *count, strings.Join(expected, ", "))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "echo \"All tests passed!\" . PHP_EOL;")
+	return nil
+}
+
+// forEachKey calls fn for each key argument, or for each line of stdin when there are none
+func forEachKey(keys []string, stdin io.Reader, fn func(string) error) error {
+	if len(keys) > 0 {
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		if err := fn(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// describe names a diff participant, which is empty for an empty ring
+func describe(target string) string {
+	if target == "" {
+		return "(none)"
+	}
+	return target
+}
+
+// phpString quotes a string as a single-quoted PHP literal
+func phpString(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	return "'" + strings.ReplaceAll(str, `'`, `\'`) + "'"
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	flexihash "github.com/mysamimi/flexiHash"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if err := run(args, strings.NewReader(stdin), &stdout, &stderr); err != nil {
+		t.Fatalf("flexihash %v failed: %v\n%s", args, err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookupMatchesLibrary(t *testing.T) {
+	fh := flexihash.NewFlexiHash()
+	fh.AddTargets([]string{"cache-1", "cache-2", "cache-3"}, 1)
+
+	out := runCommand(t, "", "lookup", "-targets", "cache-1,cache-2,cache-3", "object-a", "object-b")
+	expectedA, _ := fh.Lookup("object-a")
+	expectedB, _ := fh.Lookup("object-b")
+	expected := "object-a\t" + expectedA + "\nobject-b\t" + expectedB + "\n"
+	if out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+func TestListReadsKeysFromStdin(t *testing.T) {
+	fh := flexihash.NewFlexiHash()
+	fh.AddTarget("a", 1)
+	fh.AddTarget("b", 2)
+	fh.AddTarget("c", 1)
+
+	out := runCommand(t, "k1\nk2\n", "list", "-targets", "a,b=2,c", "-n", "3")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", out)
+	}
+	for i, key := range []string{"k1", "k2"} {
+		targets, _ := fh.LookupList(key, 3)
+		expected := key + "\t" + strings.Join(targets, ",")
+		if lines[i] != expected {
+			t.Errorf("Expected %q, got %q", expected, lines[i])
+		}
+	}
+}
+
+func TestRingFileFormats(t *testing.T) {
+	jsonPath := writeFile(t, "ring.json", `{"hasher":"md5","replicas":16,"targets":[{"name":"a","weight":1},{"name":"b","weight":2}]}`)
+	yamlPath := writeFile(t, "ring.yaml", `
+# staging cache pool
+hasher: md5
+replicas: 16
+targets:
+  - name: "a"
+  - name: b   # twice the memory
+    weight: 2
+`)
+
+	jsonRing, err := loadRingFile(jsonPath)
+	if err != nil {
+		t.Fatalf("Loading JSON failed: %v", err)
+	}
+	yamlRing, err := loadRingFile(yamlPath)
+	if err != nil {
+		t.Fatalf("Loading YAML failed: %v", err)
+	}
+	jsonPrint, _ := jsonRing.Fingerprint()
+	yamlPrint, _ := yamlRing.Fingerprint()
+	if jsonPrint != yamlPrint {
+		t.Errorf("Expected identical rings, got %s and %s", jsonPrint, yamlPrint)
+	}
+}
+
+func TestKetamaRingFileMatchesFlags(t *testing.T) {
+	path := writeFile(t, "ring.yaml", `
+ketama: true
+targets:
+  - a
+  - b
+  - c
+`)
+	fromFile := runCommand(t, "", "lookup", "-ring", path, "k1", "k2", "k3")
+	fromFlags := runCommand(t, "", "lookup", "-targets", "a,b,c", "-ketama", "k1", "k2", "k3")
+	if fromFile != fromFlags {
+		t.Errorf("Expected %q, got %q", fromFlags, fromFile)
+	}
+
+	fh := flexihash.NewKetamaFlexiHash()
+	fh.AddTargets([]string{"a", "b", "c"}, 1)
+	expected, _ := fh.Lookup("k1")
+	if !strings.HasPrefix(fromFile, "k1\t"+expected+"\n") {
+		t.Errorf("Expected k1 on %s, got %q", expected, fromFile)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"lookup", "-targets", "a", "-ketama", "-hasher", "crc32", "key"}, nil, &stdout, &stderr); err == nil {
+		t.Error("Expected error for -ketama with the crc32 hasher")
+	}
+}
+
+func TestParseYAMLRing(t *testing.T) {
+	def, err := parseYAMLRing([]byte(`hasher: 'crc32'
+replicas: 8
+probes: 0
+targets:
+  - cache-1
+  - "cache#2"
+  - name: cache-3
+    weight: 0.5
+    zone: rack-2
+  - 10.0.1.1:11211
+  - name: 10.0.1.2:11211
+    weight: 2
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	expected := []flexihash.TargetDefinition{{Name: "cache-1", Weight: 1}, {Name: "cache#2", Weight: 1}, {Name: "cache-3", Weight: 0.5, Zone: "rack-2"},
+		{Name: "10.0.1.1:11211", Weight: 1}, {Name: "10.0.1.2:11211", Weight: 2}}
+	if def.Hasher != "crc32" || def.Replicas != 8 || len(def.Targets) != len(expected) {
+		t.Fatalf("Unexpected definition %+v", def)
+	}
+	for i := range expected {
+		if def.Targets[i] != expected[i] {
+			t.Errorf("Expected target %+v, got %+v", expected[i], def.Targets[i])
+		}
+	}
+
+	for _, invalid := range []string{"replicas: many\n", "colour: blue\n", "targets:\n  - name: a\n    weight: x\n", "  - a\n"} {
+		if _, err := parseYAMLRing([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := writeFile(t, "from.yaml", "replicas: 4\ntargets:\n  - a\n  - b\n")
+	to := writeFile(t, "to.yaml", "replicas: 4\ntargets:\n  - a\n  - b\n  - c\n")
+
+	out := runCommand(t, "", "diff", from, to)
+	if !strings.Contains(out, "a -> c\t") || !strings.Contains(out, "b -> c\t") || !strings.Contains(out, "total\t") {
+		t.Errorf("Expected moves to c, got %q", out)
+	}
+	if strings.Contains(out, "-> a") || strings.Contains(out, "-> b") {
+		t.Errorf("Expected only moves to the new target, got %q", out)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	out := runCommand(t, "", "vectors", "-targets", "it's,b", "-replicas", "4", "key")
+	for _, expected := range []string{
+		"$hash = new Flexihash(new Crc32Hasher(), 4);",
+		`$hash->addTarget('it\'s', 1);`,
+		"assert($hash->lookup('key') === ",
+		"assert($hash->lookupList('key', 2) === [",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"vectors", "-targets", "a", "-hasher", "xxhash64", "key"}, nil, &stdout, &stderr); err == nil {
+		t.Error("Expected error for a hasher PHP does not ship")
+	}
+	err := run([]string{"vectors", "-targets", "a", "-ketama", "key"}, nil, &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "ketama") {
+		t.Errorf("Expected error naming ketama rings, got %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"lookup", "key"},
+		{"lookup", "-targets", "a=heavy", "key"},
+		{"diff", "only-one.json"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, &stderr); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+func TestListDistinct(t *testing.T) {
+	path := writeFile(t, "ring.yaml", `targets:
+  - name: a1
+    zone: a
+  - name: a2
+    zone: a
+  - name: b1
+    zone: b
+`)
+	out := runCommand(t, "", "list", "-ring", path, "-n", "2", "-distinct", "k1", "k2", "k3", "k4")
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		_, targets, _ := strings.Cut(line, "\t")
+		if !strings.Contains(targets, "b1") {
+			t.Errorf("Expected one target in each zone, got %q", line)
+		}
+	}
+}
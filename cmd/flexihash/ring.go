@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	flexihash "github.com/mysamimi/flexiHash"
+)
+
+// ringFlags describes a ring given on the command line
+type ringFlags struct {
+	file     *string
+	targets  *string
+	hasher   *string
+	replicas *int
+	probes   *int
+	ketama   *bool
+
+	allowEmpty bool // build an empty ring when no targets are given
+}
+
+func addRingFlags(flags *flag.FlagSet) *ringFlags {
+	return &ringFlags{
+		file:     flags.String("ring", "", "ring definition file (JSON or YAML)"),
+		targets:  flags.String("targets", "", "comma-separated targets, with optional weights as name=weight"),
+		hasher:   flags.String("hasher", "", "hasher name (default crc32, or ketama with -ketama)"),
+		replicas: flags.Int("replicas", 0, "replicas per target (0 = default)"),
+		probes:   flags.Int("probes", 0, "probes per lookup, enables multi-probe mode when above 1"),
+		ketama:   flags.Bool("ketama", false, "use libmemcached/twemproxy ketama placement"),
+	}
+}
+
+// load builds the ring from a file or from the target flags
+func (f *ringFlags) load() (*flexihash.FlexiHash, error) {
+	if *f.file != "" {
+		if *f.targets != "" {
+			return nil, errors.New("use either -ring or -targets, not both")
+		}
+		return loadRingFile(*f.file)
+	}
+	if *f.targets == "" && !f.allowEmpty {
+		return nil, errors.New("no ring given, use -ring or -targets")
+	}
+
+	def := flexihash.RingDefinition{
+		Hasher:   *f.hasher,
+		Replicas: *f.replicas,
+		Probes:   *f.probes,
+		Ketama:   *f.ketama,
+	}
+	for _, item := range strings.FieldsFunc(*f.targets, isComma) {
+		name, weight, err := parseTarget(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		def.Targets = append(def.Targets, flexihash.TargetDefinition{Name: name, Weight: weight})
+	}
+	return flexihash.NewFlexiHashFromDefinition(def)
+}
+
+func isComma(r rune) bool {
+	return r == ','
+}
+
+// parseTarget parses "name" or "name=weight"
+func parseTarget(item string) (string, float64, error) {
+	name, weightText, weighted := strings.Cut(item, "=")
+	if name == "" {
+		return "", 0, errors.New("empty target name")
+	}
+	if !weighted {
+		return name, 1, nil
+	}
+	weight, err := strconv.ParseFloat(weightText, 64)
+	if err != nil {
+		return "", 0, errors.New("invalid weight for target " + name)
+	}
+	return name, weight, nil
+}
+
+// loadRingFile reads a ring definition from a JSON or YAML file.
+// Files ending in .yaml or .yml are YAML, others are sniffed.
+func loadRingFile(path string) (*flexihash.FlexiHash, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var def flexihash.RingDefinition
+	ext := strings.ToLower(filepath.Ext(path))
+	trimmed := bytes.TrimSpace(data)
+	if ext != ".yaml" && ext != ".yml" && len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(data, &def); err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+	} else {
+		if def, err = parseYAMLRing(data); err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+	}
+	return flexihash.NewFlexiHashFromDefinition(def)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	flexihash "github.com/mysamimi/flexiHash"
+	"github.com/mysamimi/flexiHash/flexihttp"
+)
+
+// adminTokenEnv names the environment variable holding the admin token,
+// which keeps it out of the process list
+const adminTokenEnv = "FLEXIHASH_ADMIN_TOKEN"
+
+func runServe(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ringFlags := addRingFlags(flags)
+	ringFlags.allowEmpty = true
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return errors.New("serve takes no arguments")
+	}
+	ring, err := ringFlags.load()
+	if err != nil {
+		return err
+	}
+
+	adminToken := os.Getenv(adminTokenEnv)
+	handler := flexihttp.NewHandler(flexihash.NewConcurrentFlexiHashFrom(ring), adminToken)
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if adminToken == "" {
+		fmt.Fprintf(stderr, "admin endpoints disabled, set %s to enable them\n", adminTokenEnv)
+	}
+	fmt.Fprintf(stderr, "serving %d targets on %s\n", len(ring.GetAllTargets()), *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
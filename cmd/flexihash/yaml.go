@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	flexihash "github.com/mysamimi/flexiHash"
+)
+
+// parseYAMLRing parses the YAML form of a ring definition. It supports the
+// subset of YAML needed for ring files, keeping the tool free of
+// dependencies:
+//
+//	hasher: crc32
+//	replicas: 64
+//	targets:
+//	  - name: cache-1
+//	    weight: 2
+//	    zone: us-east-1a
+//	  - cache-2        # weight 1
+//	  - 10.0.1.1:11211
+func parseYAMLRing(data []byte) (flexihash.RingDefinition, error) {
+	var def flexihash.RingDefinition
+	inTargets := false
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNumber++
+		line := stripComment(scanner.Text())
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fail := func(message string) (flexihash.RingDefinition, error) {
+			return def, errors.New("line " + strconv.Itoa(lineNumber) + ": " + message)
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+		line = strings.TrimSpace(line)
+
+		if !indented {
+			key, value, found := cutMapping(line)
+			if !found {
+				return fail("expected key: value")
+			}
+			value = strings.TrimSpace(value)
+			inTargets = false
+
+			var err error
+			switch strings.TrimSpace(key) {
+			case "hasher":
+				def.Hasher = unquote(value)
+			case "replicas":
+				def.Replicas, err = strconv.Atoi(value)
+			case "probes":
+				def.Probes, err = strconv.Atoi(value)
+			case "ketama":
+				def.Ketama, err = strconv.ParseBool(value)
+			case "targets":
+				if value != "" {
+					return fail("targets must be a list")
+				}
+				inTargets = true
+			default:
+				return fail("unknown key " + key)
+			}
+			if err != nil {
+				return fail("invalid value for " + key)
+			}
+			continue
+		}
+
+		if !inTargets {
+			return fail("unexpected indentation")
+		}
+		if item, isItem := strings.CutPrefix(line, "-"); isItem {
+			item = strings.TrimSpace(item)
+			def.Targets = append(def.Targets, flexihash.TargetDefinition{Weight: 1})
+			if key, value, found := cutMapping(item); found && !isQuoted(item) {
+				line = key + ":" + value
+			} else {
+				def.Targets[len(def.Targets)-1].Name = unquote(item)
+				continue
+			}
+		}
+		if len(def.Targets) == 0 {
+			return fail("expected a list item")
+		}
+
+		target := &def.Targets[len(def.Targets)-1]
+		key, value, found := cutMapping(line)
+		if !found {
+			return fail("expected key: value")
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "name":
+			target.Name = unquote(value)
+		case "weight":
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fail("invalid weight")
+			}
+			target.Weight = weight
+		case "zone":
+			target.Zone = unquote(value)
+		default:
+			return fail("unknown target key " + key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return def, err
+	}
+	return def, nil
+}
+
+// cutMapping splits a "key: value" line. As in YAML, only a ':' followed by
+// a space or ending the line separates the key, so scalars such as
+// 10.0.1.1:11211 are not mappings.
+func cutMapping(line string) (key, value string, found bool) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == ':' && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t') {
+			return line[:i], line[i+1:], true
+		}
+	}
+	return line, "", false
+}
+
+// stripComment removes a trailing # comment outside of quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// isQuoted reports whether a scalar is wrapped in matching quotes
+func isQuoted(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]
+}
+
+// unquote removes quotes around a scalar
+func unquote(value string) string {
+	if !isQuoted(value) {
+		return value
+	}
+	if value[0] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+}
@@ -0,0 +1,203 @@
+package flexihash
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentFlexiHash is a FlexiHash that is safe for concurrent use.
+//
+// Writers copy the current ring, apply their change and publish the result
+// as a new immutable snapshot. Readers load the latest snapshot atomically,
+// so lookups never take a lock and never observe a partially applied change.
+// The hasher must be safe for concurrent use; the built-in hashers are.
+//
+// Target statuses are shared by all snapshots instead of being copied into
+// each, so marking a target down or up is O(1) and does not copy the ring.
+type ConcurrentFlexiHash struct {
+	mu       sync.Mutex // serializes writers
+	ring     atomic.Pointer[FlexiHash]
+	statuses sync.Map // target name to TargetStatus, for targets that are not up
+}
+
+// publish stores ring as the current snapshot
+func (c *ConcurrentFlexiHash) publish(ring *FlexiHash) {
+	ring.sharedStatus = &c.statuses
+	c.ring.Store(ring)
+}
+
+// NewConcurrentFlexiHash creates a new ConcurrentFlexiHash with default settings
+func NewConcurrentFlexiHash() *ConcurrentFlexiHash {
+	return NewConcurrentFlexiHashWithHasher(nil, 0)
+}
+
+// NewConcurrentFlexiHashWithHasher creates a ConcurrentFlexiHash with custom hasher and replicas
+func NewConcurrentFlexiHashWithHasher(hasher Hasher, replicas int) *ConcurrentFlexiHash {
+	c := &ConcurrentFlexiHash{}
+	c.publish(NewFlexiHashWithHasher(hasher, replicas))
+	return c
+}
+
+// NewConcurrentFlexiHashFrom creates a ConcurrentFlexiHash starting from a
+// copy of ring, such as one restored from a ring definition
+func NewConcurrentFlexiHashFrom(ring *FlexiHash) *ConcurrentFlexiHash {
+	c := &ConcurrentFlexiHash{}
+	initial := ring.clone()
+	for target := range initial.targetToPositions {
+		if status, _ := ring.Status(target); status != StatusUp {
+			c.statuses.Store(target, status)
+		}
+	}
+	clear(initial.targetStatus)
+	initial.Freeze()
+	c.publish(initial)
+	return c
+}
+
+// Snapshot returns the current immutable ring.
+// The returned FlexiHash is frozen, so changing it returns an error. Its
+// membership never changes, but its lookups follow the current target
+// statuses, so a status change is seen by later lookups on the same
+// snapshot. Use BatchSnapshot to fix the statuses too.
+func (c *ConcurrentFlexiHash) Snapshot() *FlexiHash {
+	return c.ring.Load()
+}
+
+// update applies fn to a copy of the current ring and publishes it.
+// Nothing is published if fn returns an error.
+func (c *ConcurrentFlexiHash) update(fn func(*FlexiHash) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateLocked(fn)
+}
+
+// updateLocked is update for callers holding mu
+func (c *ConcurrentFlexiHash) updateLocked(fn func(*FlexiHash) error) error {
+	next := c.ring.Load().clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	// Sort before publishing so readers never write to the snapshot
+	next.Freeze()
+	c.publish(next)
+	return nil
+}
+
+// AddTarget adds a target to the hash ring with optional weight
+func (c *ConcurrentFlexiHash) AddTarget(target string, weight float64) error {
+	return c.update(func(fh *FlexiHash) error {
+		return fh.AddTarget(target, weight)
+	})
+}
+
+// AddTargets adds multiple targets with optional weight.
+// Either all targets are added or, on error, none are.
+func (c *ConcurrentFlexiHash) AddTargets(targets []string, weight float64) error {
+	return c.update(func(fh *FlexiHash) error {
+		return fh.AddTargets(targets, weight)
+	})
+}
+
+// RemoveTarget removes a target from the hash ring
+func (c *ConcurrentFlexiHash) RemoveTarget(target string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.updateLocked(func(fh *FlexiHash) error {
+		return fh.RemoveTarget(target)
+	})
+	if err != nil {
+		return err
+	}
+	// Readers of the previous snapshot may still look up the target, so
+	// its status is forgotten only once the snapshot without it is published
+	c.statuses.Delete(target)
+	return nil
+}
+
+// ReweightTarget changes the weight of an existing target
+func (c *ConcurrentFlexiHash) ReweightTarget(target string, weight float64) error {
+	return c.update(func(fh *FlexiHash) error {
+		return fh.ReweightTarget(target, weight)
+	})
+}
+
+// SetTargetZone labels a target with its failure domain
+func (c *ConcurrentFlexiHash) SetTargetZone(target, zone string) error {
+	return c.update(func(fh *FlexiHash) error {
+		return fh.SetTargetZone(target, zone)
+	})
+}
+
+// SetTargetStatus sets the status of a target in O(1), without copying the
+// ring. Lookups skip targets that are not up.
+func (c *ConcurrentFlexiHash) SetTargetStatus(target string, status TargetStatus) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.ring.Load().targetToPositions[target]; !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+	if !status.valid() {
+		return errors.New("Invalid status")
+	}
+	if status == StatusUp {
+		c.statuses.Delete(target)
+	} else {
+		c.statuses.Store(target, status)
+	}
+	return nil
+}
+
+// Status returns the status of a target
+func (c *ConcurrentFlexiHash) Status(target string) (TargetStatus, error) {
+	return c.Snapshot().Status(target)
+}
+
+// GetAllTargets returns a list of all potential targets
+func (c *ConcurrentFlexiHash) GetAllTargets() []string {
+	return c.Snapshot().GetAllTargets()
+}
+
+// Lookup finds the target for a given resource
+func (c *ConcurrentFlexiHash) Lookup(resource string) (string, error) {
+	return c.Snapshot().Lookup(resource)
+}
+
+// LookupList returns a list of targets for the resource, in order of precedence
+func (c *ConcurrentFlexiHash) LookupList(resource string, requestedCount int) ([]string, error) {
+	return c.Snapshot().LookupList(resource, requestedCount)
+}
+
+// LookupListDistinct returns targets for the resource in distinct failure
+// domains where possible, in order of precedence
+func (c *ConcurrentFlexiHash) LookupListDistinct(resource string, requestedCount int) ([]string, error) {
+	return c.Snapshot().LookupListDistinct(resource, requestedCount)
+}
+
+// LookupBytes finds the target for a resource given as bytes
+func (c *ConcurrentFlexiHash) LookupBytes(resource []byte) (string, error) {
+	return c.Snapshot().LookupBytes(resource)
+}
+
+// LookupUint64 finds the target for an integer resource
+func (c *ConcurrentFlexiHash) LookupUint64(resource uint64) (string, error) {
+	return c.Snapshot().LookupUint64(resource)
+}
+
+// LookupKey finds the target for a Key
+func (c *ConcurrentFlexiHash) LookupKey(resource Key) (string, error) {
+	return c.Snapshot().LookupKey(resource)
+}
+
+// LookupListKey returns a list of targets for a Key, in order of precedence
+func (c *ConcurrentFlexiHash) LookupListKey(resource Key, requestedCount int) ([]string, error) {
+	return c.Snapshot().LookupListKey(resource, requestedCount)
+}
+
+// AppendLookupList appends the targets for the resource to dst, in order of
+// precedence, and returns the extended slice
+func (c *ConcurrentFlexiHash) AppendLookupList(dst []string, resource string, requestedCount int) ([]string, error) {
+	return c.Snapshot().AppendLookupList(dst, resource, requestedCount)
+}
@@ -0,0 +1,143 @@
+package flexihash
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentFlexiHashMatchesFlexiHash(t *testing.T) {
+	fh := NewFlexiHash()
+	cfh := NewConcurrentFlexiHash()
+	targets := []string{"cache-1", "cache-2", "cache-3", "cache-4"}
+	fh.AddTargets(targets, 1)
+	if err := cfh.AddTargets(targets, 1); err != nil {
+		t.Fatalf("AddTargets failed: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		expected, _ := fh.LookupList(key, 2)
+		got, err := cfh.LookupList(key, 2)
+		if err != nil {
+			t.Fatalf("LookupList failed: %v", err)
+		}
+		if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+			t.Fatalf("Key %s: expected %v, got %v", key, expected, got)
+		}
+	}
+}
+
+func TestConcurrentFlexiHashAddTargetsAtomic(t *testing.T) {
+	cfh := NewConcurrentFlexiHash()
+	cfh.AddTarget("t2", 1)
+
+	err := cfh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	if err == nil {
+		t.Fatal("Expected error when adding duplicate target")
+	}
+	targets := cfh.GetAllTargets()
+	if len(targets) != 1 || targets[0] != "t2" {
+		t.Errorf("Expected [t2] after failed AddTargets, got %v", targets)
+	}
+}
+
+func TestConcurrentFlexiHashSnapshotIsImmutable(t *testing.T) {
+	cfh := NewConcurrentFlexiHash()
+	cfh.AddTargets([]string{"t1", "t2"}, 1)
+
+	snapshot := cfh.Snapshot()
+	cfh.AddTarget("t3", 1)
+	cfh.RemoveTarget("t1")
+
+	if len(snapshot.GetAllTargets()) != 2 {
+		t.Errorf("Snapshot changed after update: %v", snapshot.GetAllTargets())
+	}
+	if len(cfh.GetAllTargets()) != 2 {
+		t.Errorf("Expected 2 targets, got %v", cfh.GetAllTargets())
+	}
+	if _, err := cfh.Snapshot().Lookup("resource"); err != nil {
+		t.Errorf("Lookup failed: %v", err)
+	}
+}
+
+// Run with -race to check that lookups never write to shared state
+func TestConcurrentFlexiHashParallelLookups(t *testing.T) {
+	cfh := NewConcurrentFlexiHash()
+	for i := 0; i < 10; i++ {
+		cfh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := "key-" + strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				if _, err := cfh.Lookup(key); err != nil {
+					t.Errorf("Lookup failed: %v", err)
+					return
+				}
+				if _, err := cfh.LookupList(key, 3); err != nil {
+					t.Errorf("LookupList failed: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+
+	for i := 0; i < 100; i++ {
+		target := "extra" + strconv.Itoa(i%5)
+		if err := cfh.AddTarget(target, 1); err != nil {
+			t.Errorf("AddTarget failed: %v", err)
+		}
+		if err := cfh.RemoveTarget(target); err != nil {
+			t.Errorf("RemoveTarget failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkConcurrentLookupParallel(b *testing.B) {
+	cfh := NewConcurrentFlexiHash()
+	for i := 0; i < 10; i++ {
+		cfh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cfh.Lookup("resource" + strconv.Itoa(i%1000))
+			i++
+		}
+	})
+}
+
+func TestNewConcurrentFlexiHashFrom(t *testing.T) {
+	fh := NewFlexiHashWithHasher(&Md5Hasher{}, 16)
+	fh.AddTargets([]string{"t1", "t2"}, 1)
+
+	cfh := NewConcurrentFlexiHashFrom(fh)
+	fh.AddTarget("t3", 1)
+	if len(cfh.GetAllTargets()) != 2 {
+		t.Errorf("Expected the source ring to be copied, got %v", cfh.GetAllTargets())
+	}
+
+	if err := cfh.ReweightTarget("t1", 3); err != nil {
+		t.Fatalf("ReweightTarget failed: %v", err)
+	}
+	if len(cfh.Snapshot().targetToPositions["t1"]) != 48 {
+		t.Errorf("Expected 48 positions for t1, got %d", len(cfh.Snapshot().targetToPositions["t1"]))
+	}
+	if err := cfh.ReweightTarget("t3", 1); err == nil {
+		t.Error("Expected error when reweighting non-existent target")
+	}
+}
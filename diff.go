@@ -0,0 +1,162 @@
+package flexihash
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// hashSpaceSize is the size of the 32-bit hash space that ring positions live in
+const hashSpaceSize = 1 << 32
+
+// Arc is a half-open range [Start, End) of the 32-bit hash space.
+// Arithmetic is modulo 2^32, so an arc with End < Start wraps around the
+// end of the hash space, and an arc with Start == End covers all of it.
+// A key belongs to an arc when the ring's hasher maps it into the range.
+type Arc struct {
+	Start, End int
+}
+
+// Contains reports whether a hash value falls within the arc
+func (a Arc) Contains(hash int) bool {
+	if a.Start == a.End {
+		return true
+	}
+	return uint32(hash-a.Start) < uint32(a.End-a.Start)
+}
+
+// Size returns the number of hash values in the arc
+func (a Arc) Size() uint64 {
+	if a.Start == a.End {
+		return hashSpaceSize
+	}
+	return uint64(uint32(a.End - a.Start))
+}
+
+// Fraction returns the share of the hash space covered by the arc
+func (a Arc) Fraction() float64 {
+	return float64(a.Size()) / hashSpaceSize
+}
+
+// Move is an arc of the hash space whose owner changes between two rings.
+// From or To is empty when the corresponding ring has no targets.
+type Move struct {
+	From string
+	To   string
+	Arc  Arc
+}
+
+// Transfer is the share of the hash space moving from one target to another
+type Transfer struct {
+	From     string
+	To       string
+	Fraction float64
+}
+
+// RingDiff describes how keys move between two rings
+type RingDiff struct {
+	Moves []Move
+}
+
+// Diff returns the arcs of the hash space whose owner differs between two
+// rings, computed exactly from their sorted positions. Both rings must use
+// the same hasher and must not be in multi-probe mode, where ownership
+// does not follow arcs. Adjacent arcs with the same owners are merged.
+func Diff(from, to *FlexiHash) (*RingDiff, error) {
+	if from.probes > 1 || to.probes > 1 {
+		return nil, errors.New("Diff does not support multi-probe rings")
+	}
+	if !reflect.DeepEqual(from.hasher, to.hasher) {
+		return nil, errors.New("Rings use different hashers")
+	}
+	from.sortPositionTargets()
+	to.sortPositionTargets()
+
+	// Between consecutive boundaries of either ring, every key has the
+	// same owner in each ring
+	boundaries := make([]int, 0, len(from.sortedPositions)+len(to.sortedPositions))
+	boundaries = append(boundaries, from.sortedPositions...)
+	boundaries = append(boundaries, to.sortedPositions...)
+	sort.Ints(boundaries)
+	boundaries = slices.Compact(boundaries)
+
+	diff := &RingDiff{}
+	for i, boundary := range boundaries {
+		previous := boundaries[len(boundaries)-1]
+		if i > 0 {
+			previous = boundaries[i-1]
+		}
+		move := Move{
+			From: from.ownerAt(boundary),
+			To:   to.ownerAt(boundary),
+			Arc:  Arc{Start: previous + 1, End: boundary + 1},
+		}
+		if move.From == move.To {
+			continue
+		}
+
+		// Merge with the previous arc when they are adjacent and move the same way
+		if n := len(diff.Moves); n > 0 {
+			last := &diff.Moves[n-1]
+			if last.From == move.From && last.To == move.To && last.Arc.End == move.Arc.Start {
+				last.Arc.End = move.Arc.End
+				continue
+			}
+		}
+		diff.Moves = append(diff.Moves, move)
+	}
+
+	// The last arc may continue into the first one across the wrap-around
+	if n := len(diff.Moves); n > 1 {
+		first, last := diff.Moves[0], diff.Moves[n-1]
+		if first.From == last.From && first.To == last.To && uint32(last.Arc.End) == uint32(first.Arc.Start) {
+			diff.Moves[0].Arc.Start = last.Arc.Start
+			diff.Moves = diff.Moves[:n-1]
+		}
+	}
+	return diff, nil
+}
+
+// Fraction returns the share of the hash space that changes owner
+func (d *RingDiff) Fraction() float64 {
+	var size uint64
+	for _, move := range d.Moves {
+		size += move.Arc.Size()
+	}
+	return float64(size) / hashSpaceSize
+}
+
+// Transfers returns the share of the hash space moving between each pair
+// of targets, sorted by source and then destination
+func (d *RingDiff) Transfers() []Transfer {
+	sizes := make(map[[2]string]uint64)
+	for _, move := range d.Moves {
+		sizes[[2]string{move.From, move.To}] += move.Arc.Size()
+	}
+
+	transfers := make([]Transfer, 0, len(sizes))
+	for pair, size := range sizes {
+		transfers = append(transfers, Transfer{
+			From:     pair[0],
+			To:       pair[1],
+			Fraction: float64(size) / hashSpaceSize,
+		})
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].From != transfers[j].From {
+			return transfers[i].From < transfers[j].From
+		}
+		return transfers[i].To < transfers[j].To
+	})
+	return transfers
+}
+
+// ownerAt returns the target owning the hash value, or "" for an empty
+// ring. The ring must be sorted.
+func (fh *FlexiHash) ownerAt(hash int) string {
+	if fh.positionCount == 0 {
+		return ""
+	}
+	return fh.positionToTarget[fh.sortedPositions[searchPosition(fh.sortedPositions, hash)]]
+}
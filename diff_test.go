@@ -0,0 +1,154 @@
+package flexihash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestArc(t *testing.T) {
+	arc := Arc{Start: 10, End: 20}
+	if !arc.Contains(10) || !arc.Contains(19) || arc.Contains(20) || arc.Contains(9) {
+		t.Error("Arc [10, 20) has wrong bounds")
+	}
+	if arc.Size() != 10 {
+		t.Errorf("Expected size 10, got %d", arc.Size())
+	}
+
+	wrapping := Arc{Start: math.MaxInt32 - 4, End: math.MinInt32 + 5}
+	if !wrapping.Contains(math.MaxInt32) || !wrapping.Contains(math.MinInt32) || wrapping.Contains(0) {
+		t.Error("Wrapping arc has wrong bounds")
+	}
+	if wrapping.Size() != 10 {
+		t.Errorf("Expected size 10, got %d", wrapping.Size())
+	}
+
+	whole := Arc{Start: 5, End: 5}
+	if !whole.Contains(0) || whole.Fraction() != 1 {
+		t.Error("Arc with Start == End should cover the whole hash space")
+	}
+}
+
+func TestDiffExact(t *testing.T) {
+	mockHasher := &MockHasher{}
+	from := NewFlexiHashWithHasher(mockHasher, 1)
+	to := NewFlexiHashWithHasher(mockHasher, 1)
+
+	mockHasher.hashValue = 10
+	from.AddTarget("a", 1)
+	to.AddTarget("a", 1)
+	mockHasher.hashValue = 20
+	from.AddTarget("b", 1)
+	to.AddTarget("b", 1)
+	mockHasher.hashValue = 15
+	to.AddTarget("c", 1)
+
+	diff, err := Diff(from, to)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diff.Moves) != 1 {
+		t.Fatalf("Expected 1 move, got %v", diff.Moves)
+	}
+	expected := Move{From: "b", To: "c", Arc: Arc{Start: 11, End: 16}}
+	if diff.Moves[0] != expected {
+		t.Errorf("Expected %v, got %v", expected, diff.Moves[0])
+	}
+	if diff.Fraction() != 5.0/hashSpaceSize {
+		t.Errorf("Expected fraction 5/2^32, got %g", diff.Fraction())
+	}
+}
+
+func TestDiffMatchesLookups(t *testing.T) {
+	from := NewFlexiHash()
+	from.AddTargets([]string{"cache-1", "cache-2", "cache-3", "cache-4"}, 1)
+	to := NewFlexiHash()
+	to.AddTargets([]string{"cache-1", "cache-2", "cache-4", "cache-5"}, 1)
+
+	diff, err := Diff(from, to)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	hasher := &Crc32Hasher{}
+	moved := 0
+	for i := 0; i < 5000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		before, _ := from.Lookup(key)
+		after, _ := to.Lookup(key)
+
+		var found *Move
+		for j := range diff.Moves {
+			if diff.Moves[j].Arc.Contains(hasher.Hash(key)) {
+				found = &diff.Moves[j]
+				break
+			}
+		}
+		if before == after {
+			if found != nil {
+				t.Fatalf("Key %s stays on %s but is in move %v", key, before, *found)
+			}
+			continue
+		}
+		moved++
+		if found == nil || found.From != before || found.To != after {
+			t.Fatalf("Key %s moves %s -> %s but diff has %v", key, before, after, found)
+		}
+	}
+
+	sampled := float64(moved) / 5000
+	if math.Abs(sampled-diff.Fraction()) > 0.03 {
+		t.Errorf("Sampled fraction %.3f differs from exact fraction %.3f", sampled, diff.Fraction())
+	}
+
+	total := 0.0
+	for _, transfer := range diff.Transfers() {
+		if transfer.From == transfer.To {
+			t.Errorf("Transfer to the same target: %v", transfer)
+		}
+		total += transfer.Fraction
+	}
+	if math.Abs(total-diff.Fraction()) > 1e-9 {
+		t.Errorf("Transfers sum to %g, expected %g", total, diff.Fraction())
+	}
+}
+
+func TestDiffAddingTargetOnlyMovesToIt(t *testing.T) {
+	from := NewFlexiHash()
+	from.AddTargets([]string{"a", "b", "c"}, 1)
+	to := NewFlexiHash()
+	to.AddTargets([]string{"a", "b", "c", "d"}, 1)
+
+	diff, _ := Diff(from, to)
+	for _, move := range diff.Moves {
+		if move.To != "d" {
+			t.Errorf("Expected every move to go to d, got %v", move)
+		}
+	}
+	if fraction := diff.Fraction(); fraction < 0.1 || fraction > 0.4 {
+		t.Errorf("Expected roughly a quarter of the keyspace to move, got %.3f", fraction)
+	}
+}
+
+func TestDiffEmptyAndIdentical(t *testing.T) {
+	empty := NewFlexiHash()
+	ring := NewFlexiHash()
+	ring.AddTarget("only", 1)
+
+	diff, _ := Diff(empty, ring)
+	if len(diff.Moves) != 1 || diff.Moves[0].From != "" || diff.Moves[0].To != "only" || diff.Fraction() != 1 {
+		t.Errorf("Expected the whole keyspace to move to only, got %v", diff.Moves)
+	}
+
+	diff, _ = Diff(ring, ring)
+	if len(diff.Moves) != 0 {
+		t.Errorf("Expected no moves between identical rings, got %v", diff.Moves)
+	}
+
+	if _, err := Diff(ring, NewFlexiHashWithHasher(&Md5Hasher{}, 0)); err == nil {
+		t.Error("Expected error for rings with different hashers")
+	}
+	if _, err := Diff(ring, NewMultiProbeFlexiHash(nil, 0)); err == nil {
+		t.Error("Expected error for multi-probe ring")
+	}
+}
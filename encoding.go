@@ -0,0 +1,343 @@
+package flexihash
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// binaryMagic and binaryVersion prefix the binary ring encoding
+const (
+	binaryMagic   = "FXH"
+	binaryVersion = 1
+)
+
+// Flags of the binary ring encoding
+const (
+	binaryFlagKetama = 1 << iota
+	binaryFlagZones  // each target is followed by its zone
+)
+
+var (
+	hasherRegistryMu sync.RWMutex
+	hasherRegistry   = map[string]func() Hasher{
+		"crc32":       func() Hasher { return &Crc32Hasher{} },
+		"md5":         func() Hasher { return &Md5Hasher{} },
+		"ketama":      func() Hasher { return &KetamaHasher{} },
+		"xxhash64":    func() Hasher { return &XxHash64Hasher{} },
+		"murmur3":     func() Hasher { return &Murmur3Hasher{} },
+		"murmur3-128": func() Hasher { return &Murmur3x128Hasher{} },
+		"fnv1a32":     func() Hasher { return &Fnv1a32Hasher{} },
+		"fnv1a64":     func() Hasher { return &Fnv1a64Hasher{} },
+		"siphash":     func() Hasher { return &SipHasher{} },
+	}
+)
+
+// RegisterHasher registers a hasher factory under name, so rings using
+// an equal hasher can be encoded and restored. Register configured
+// hashers, such as a seeded XxHash64Hasher or a keyed SipHasher, under
+// their own name. Registering an existing name replaces it.
+func RegisterHasher(name string, factory func() Hasher) {
+	hasherRegistryMu.Lock()
+	defer hasherRegistryMu.Unlock()
+	hasherRegistry[name] = factory
+}
+
+// NewHasher returns a new hasher registered under name
+func NewHasher(name string) (Hasher, error) {
+	hasherRegistryMu.RLock()
+	defer hasherRegistryMu.RUnlock()
+
+	factory, exists := hasherRegistry[name]
+	if !exists {
+		return nil, errors.New("Hasher '" + name + "' is not registered")
+	}
+	return factory(), nil
+}
+
+// HasherName returns the name a hasher is registered under.
+// A hasher matches a name when it is deeply equal to the hasher its factory returns.
+func HasherName(hasher Hasher) (string, error) {
+	hasherRegistryMu.RLock()
+	defer hasherRegistryMu.RUnlock()
+
+	// Check names in order so the result is deterministic
+	names := make([]string, 0, len(hasherRegistry))
+	for name := range hasherRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if reflect.DeepEqual(hasherRegistry[name](), hasher) {
+			return name, nil
+		}
+	}
+	return "", errors.New("Hasher is not registered")
+}
+
+// RingDefinition describes a FlexiHash completely, with targets sorted by name
+type RingDefinition struct {
+	Hasher   string             `json:"hasher"`
+	Replicas int                `json:"replicas"`
+	Probes   int                `json:"probes,omitempty"`
+	Ketama   bool               `json:"ketama,omitempty"`
+	Targets  []TargetDefinition `json:"targets"`
+}
+
+// TargetDefinition describes a target of a RingDefinition
+type TargetDefinition struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Zone   string  `json:"zone,omitempty"`
+}
+
+// Definition returns the definition of the ring.
+// It fails if the ring's hasher is not registered.
+func (fh *FlexiHash) Definition() (RingDefinition, error) {
+	name, err := HasherName(fh.hasher)
+	if err != nil {
+		return RingDefinition{}, err
+	}
+
+	def := RingDefinition{
+		Hasher:   name,
+		Replicas: fh.replicas,
+		Probes:   fh.probes,
+		Ketama:   fh.ketama,
+		Targets:  make([]TargetDefinition, 0, len(fh.targetToWeight)),
+	}
+	for target, weight := range fh.targetToWeight {
+		def.Targets = append(def.Targets, TargetDefinition{Name: target, Weight: weight, Zone: fh.targetZone[target]})
+	}
+	sort.Slice(def.Targets, func(i, j int) bool {
+		return def.Targets[i].Name < def.Targets[j].Name
+	})
+	return def, nil
+}
+
+// maxRingPositions bounds the positions of a restored ring, since
+// definitions may come from other services or from disk
+const maxRingPositions = 1 << 24
+
+// NewFlexiHashFromDefinition creates a FlexiHash from a definition.
+// An empty hasher means crc32, or ketama for ketama definitions, which also
+// default to 160 points per target and fail with any other hasher.
+// It fails for weights that are negative or not finite, and for
+// definitions that would place more than 16M positions.
+func NewFlexiHashFromDefinition(def RingDefinition) (*FlexiHash, error) {
+	if def.Ketama {
+		if def.Hasher == "" {
+			def.Hasher = "ketama"
+		}
+		if def.Hasher != "ketama" {
+			return nil, errors.New("Ketama rings require the ketama hasher")
+		}
+		if def.Replicas == 0 {
+			def.Replicas = ketamaPointsPerServer
+		}
+	} else if def.Hasher == "" {
+		def.Hasher = "crc32"
+	}
+	hasher, err := NewHasher(def.Hasher)
+	if err != nil {
+		return nil, err
+	}
+	if def.Replicas < 0 || def.Replicas > maxTargetPositions ||
+		def.Probes < 0 || def.Probes > maxTargetPositions {
+		return nil, errors.New("Invalid ring definition")
+	}
+	if err := def.checkSize(); err != nil {
+		return nil, err
+	}
+
+	fh := NewFlexiHashWithHasher(hasher, def.Replicas)
+	fh.probes = def.Probes
+	fh.ketama = def.Ketama
+	for _, target := range def.Targets {
+		if err := fh.AddTarget(target.Name, target.Weight); err != nil {
+			return nil, err
+		}
+		if target.Zone != "" {
+			fh.targetZone[target.Name] = target.Zone
+		}
+	}
+	return fh, nil
+}
+
+// checkSize fails if restoring the definition would place more than
+// maxRingPositions positions
+func (def RingDefinition) checkSize() error {
+	replicas := float64(def.Replicas)
+	if replicas == 0 {
+		replicas = 64
+	}
+	total := 0.0
+	for _, target := range def.Targets {
+		weight := target.Weight
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return errors.New("Invalid weight for target '" + target.Name + "'")
+		}
+		if weight == 0 || def.Ketama {
+			// Ketama targets share replicas per target between them
+			weight = 1
+		}
+		total += replicas * weight
+	}
+	if total > maxRingPositions {
+		return errors.New("Ring definition is too large")
+	}
+	return nil
+}
+
+// MarshalJSON encodes the ring definition as JSON
+func (fh *FlexiHash) MarshalJSON() ([]byte, error) {
+	def, err := fh.Definition()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(def)
+}
+
+// UnmarshalJSON replaces the ring with one restored from JSON
+func (fh *FlexiHash) UnmarshalJSON(data []byte) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
+	var def RingDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return err
+	}
+	restored, err := NewFlexiHashFromDefinition(def)
+	if err != nil {
+		return err
+	}
+	*fh = *restored
+	return nil
+}
+
+// MarshalBinary encodes the ring definition in a compact versioned format
+func (fh *FlexiHash) MarshalBinary() ([]byte, error) {
+	def, err := fh.Definition()
+	if err != nil {
+		return nil, err
+	}
+
+	data := append([]byte(binaryMagic), binaryVersion)
+	var flags byte
+	if def.Ketama {
+		flags |= binaryFlagKetama
+	}
+	for _, target := range def.Targets {
+		if target.Zone != "" {
+			flags |= binaryFlagZones
+		}
+	}
+	data = append(data, flags)
+	data = binary.AppendUvarint(data, uint64(def.Replicas))
+	data = binary.AppendUvarint(data, uint64(def.Probes))
+	data = appendString(data, def.Hasher)
+	data = binary.AppendUvarint(data, uint64(len(def.Targets)))
+	for _, target := range def.Targets {
+		data = appendString(data, target.Name)
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(target.Weight))
+		if flags&binaryFlagZones != 0 {
+			data = appendString(data, target.Zone)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces the ring with one restored from MarshalBinary output
+func (fh *FlexiHash) UnmarshalBinary(data []byte) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
+	errInvalid := errors.New("Invalid ring encoding")
+	if len(data) < len(binaryMagic)+2 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return errInvalid
+	}
+	if data[len(binaryMagic)] != binaryVersion {
+		return errors.New("Unsupported ring encoding version")
+	}
+	flags := data[len(binaryMagic)+1]
+	if flags&^(binaryFlagKetama|binaryFlagZones) != 0 {
+		return errInvalid
+	}
+	r := &byteReader{data: data[len(binaryMagic)+2:]}
+
+	def := RingDefinition{
+		Ketama:   flags&binaryFlagKetama != 0,
+		Replicas: int(r.uvarint()),
+		Probes:   int(r.uvarint()),
+		Hasher:   r.string(),
+	}
+	count := r.uvarint()
+	if r.err || count > uint64(len(r.data)) {
+		return errInvalid
+	}
+	def.Targets = make([]TargetDefinition, count)
+	for i := range def.Targets {
+		def.Targets[i].Name = r.string()
+		def.Targets[i].Weight = math.Float64frombits(r.uint64())
+		if flags&binaryFlagZones != 0 {
+			def.Targets[i].Zone = r.string()
+		}
+	}
+	if r.err || len(r.data) != 0 {
+		return errInvalid
+	}
+
+	restored, err := NewFlexiHashFromDefinition(def)
+	if err != nil {
+		return err
+	}
+	*fh = *restored
+	return nil
+}
+
+// appendString appends a length-prefixed string
+func appendString(data []byte, str string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(str)))
+	return append(data, str...)
+}
+
+// byteReader decodes the binary ring encoding, recording the first error
+type byteReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *byteReader) uvarint() uint64 {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = true
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *byteReader) string() string {
+	length := r.uvarint()
+	if r.err || length > uint64(len(r.data)) {
+		r.err = true
+		return ""
+	}
+	str := string(r.data[:length])
+	r.data = r.data[length:]
+	return str
+}
+
+func (r *byteReader) uint64() uint64 {
+	if r.err || len(r.data) < 8 {
+		r.err = true
+		return 0
+	}
+	value := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return value
+}
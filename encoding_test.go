@@ -0,0 +1,210 @@
+package flexihash
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func assertSameLookups(t *testing.T, expected, got *FlexiHash) {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		want, _ := expected.LookupList(key, 2)
+		have, _ := got.LookupList(key, 2)
+		if len(want) != len(have) {
+			t.Fatalf("Key %s: expected %v, got %v", key, want, have)
+		}
+		for j := range want {
+			if want[j] != have[j] {
+				t.Fatalf("Key %s: expected %v, got %v", key, want, have)
+			}
+		}
+	}
+}
+
+func encodingTestRings() map[string]*FlexiHash {
+	rings := map[string]*FlexiHash{
+		"default":     NewFlexiHash(),
+		"md5":         NewFlexiHashWithHasher(&Md5Hasher{}, 32),
+		"multi-probe": NewMultiProbeFlexiHash(&XxHash64Hasher{}, 0),
+		"ketama":      NewKetamaFlexiHash(),
+	}
+	for _, fh := range rings {
+		fh.AddTargets([]string{"cache-1", "cache-2", "cache-3"}, 1)
+		fh.AddTarget("cache-4", 2.5)
+	}
+	return rings
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for name, fh := range encodingTestRings() {
+		data, err := json.Marshal(fh)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", name, err)
+		}
+
+		var restored FlexiHash
+		if err := json.Unmarshal(data, &restored); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", name, err)
+		}
+		assertSameLookups(t, fh, &restored)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"b", "a"}, 1)
+
+	data, _ := json.Marshal(fh)
+	expected := `{"hasher":"crc32","replicas":64,"targets":[{"name":"a","weight":1},{"name":"b","weight":1}]}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for name, fh := range encodingTestRings() {
+		data, err := fh.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: MarshalBinary failed: %v", name, err)
+		}
+
+		restored := &FlexiHash{}
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: UnmarshalBinary failed: %v", name, err)
+		}
+		assertSameLookups(t, fh, restored)
+
+		again, _ := restored.MarshalBinary()
+		if string(again) != string(data) {
+			t.Errorf("%s: encoding is not stable", name)
+		}
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"a", "b"}, 1)
+	data, _ := fh.MarshalBinary()
+
+	invalid := map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXX"), data[3:]...),
+		"version":   append(append([]byte("FXH"), 99), data[4:]...),
+		"truncated": data[:len(data)-3],
+		"trailing":  append(append([]byte{}, data...), 0),
+	}
+	for name, encoded := range invalid {
+		if err := (&FlexiHash{}).UnmarshalBinary(encoded); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRestoreRejectsOversizedRings(t *testing.T) {
+	definitions := map[string]RingDefinition{
+		"replicas":      {Hasher: "crc32", Replicas: 1 << 40, Targets: []TargetDefinition{{Name: "a", Weight: 1}}},
+		"weight":        {Hasher: "crc32", Replicas: 64, Targets: []TargetDefinition{{Name: "a", Weight: 1e15}}},
+		"negative":      {Hasher: "crc32", Replicas: 64, Targets: []TargetDefinition{{Name: "a", Weight: -1}}},
+		"nan":           {Hasher: "crc32", Replicas: 64, Targets: []TargetDefinition{{Name: "a", Weight: math.NaN()}}},
+		"infinite":      {Hasher: "crc32", Replicas: 64, Targets: []TargetDefinition{{Name: "a", Weight: math.Inf(1)}}},
+		"probes":        {Hasher: "crc32", Replicas: 1, Probes: 1 << 40, Targets: []TargetDefinition{{Name: "a", Weight: 1}}},
+		"ketama weight": {Hasher: "ketama", Replicas: 160, Ketama: true, Targets: []TargetDefinition{{Name: "a", Weight: 1e300}}},
+	}
+	many := RingDefinition{Hasher: "crc32", Replicas: 1 << 20}
+	for i := 0; i < 20; i++ {
+		many.Targets = append(many.Targets, TargetDefinition{Name: strconv.Itoa(i), Weight: 1})
+	}
+	definitions["total"] = many
+
+	for name, def := range definitions {
+		if _, err := NewFlexiHashFromDefinition(def); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// The binary encoding goes through the same checks
+	fh := NewFlexiHash()
+	fh.AddTarget("a", 1)
+	data, _ := fh.MarshalBinary()
+	weight := len(data) - 8
+	binary.LittleEndian.PutUint64(data[weight:], math.Float64bits(math.NaN()))
+	if err := (&FlexiHash{}).UnmarshalBinary(data); err == nil {
+		t.Error("Expected error for a NaN weight in the binary encoding")
+	}
+}
+
+func TestKetamaDefinitionDefaults(t *testing.T) {
+	expected := NewKetamaFlexiHash()
+	expected.AddTargets([]string{"a", "b", "c"}, 1)
+
+	fh, err := NewFlexiHashFromDefinition(RingDefinition{
+		Ketama:  true,
+		Targets: []TargetDefinition{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+	})
+	if err != nil {
+		t.Fatalf("NewFlexiHashFromDefinition failed: %v", err)
+	}
+	if fh.replicas != ketamaPointsPerServer {
+		t.Errorf("Expected %d replicas, got %d", ketamaPointsPerServer, fh.replicas)
+	}
+	assertSameLookups(t, expected, fh)
+
+	def := RingDefinition{Hasher: "crc32", Ketama: true, Targets: []TargetDefinition{{Name: "a"}}}
+	if _, err := NewFlexiHashFromDefinition(def); err == nil {
+		t.Error("Expected error for a ketama ring with the crc32 hasher")
+	}
+}
+
+func TestUnmarshalFrozenRing(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTarget("a", 1)
+	data, _ := fh.MarshalBinary()
+	text, _ := json.Marshal(fh)
+
+	frozen := NewFlexiHash()
+	frozen.AddTarget("b", 1)
+	frozen.Freeze()
+	if err := frozen.UnmarshalBinary(data); err == nil {
+		t.Error("Expected UnmarshalBinary to refuse a frozen ring")
+	}
+	if err := json.Unmarshal(text, frozen); err == nil {
+		t.Error("Expected UnmarshalJSON to refuse a frozen ring")
+	}
+	if targets := frozen.GetAllTargets(); len(targets) != 1 || targets[0] != "b" {
+		t.Errorf("Expected frozen ring unchanged, got %v", targets)
+	}
+}
+
+func TestHasherRegistry(t *testing.T) {
+	seeded := &XxHash64Hasher{Seed: 42}
+	fh := NewFlexiHashWithHasher(seeded, 0)
+	fh.AddTarget("a", 1)
+	if _, err := json.Marshal(fh); err == nil {
+		t.Error("Expected error for unregistered hasher")
+	}
+	if _, err := NewHasher("missing"); err == nil {
+		t.Error("Expected error for unknown hasher name")
+	}
+
+	RegisterHasher("xxhash64-seed42", func() Hasher { return &XxHash64Hasher{Seed: 42} })
+	name, err := HasherName(seeded)
+	if err != nil || name != "xxhash64-seed42" {
+		t.Errorf("Expected xxhash64-seed42, got %q (%v)", name, err)
+	}
+
+	data, err := json.Marshal(fh)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var restored FlexiHash
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if restored.hasher.(*XxHash64Hasher).Seed != 42 {
+		t.Error("Expected restored hasher to keep its seed")
+	}
+}
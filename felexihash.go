@@ -1,12 +1,17 @@
 package flexihash
 
 import (
+	"cmp"
 	"crypto/md5"
-	"encoding/hex"
+	"encoding/binary"
 	"errors"
 	"hash/crc32"
+	"math"
+	"slices"
 	"sort"
 	"strconv"
+	"sync"
+	"unsafe"
 )
 
 // Hasher is the interface for hash functions
@@ -14,12 +19,26 @@ type Hasher interface {
 	Hash(string) int
 }
 
+// Ring is the interface shared by the consistent hashing strategies
+type Ring interface {
+	AddTarget(target string, weight float64) error
+	RemoveTarget(target string) error
+	GetAllTargets() []string
+	Lookup(resource string) (string, error)
+	LookupList(resource string, requestedCount int) ([]string, error)
+}
+
+var (
+	_ Ring = (*FlexiHash)(nil)
+	_ Ring = (*ConcurrentFlexiHash)(nil)
+)
+
 // Crc32Hasher uses CRC32 to hash values (matches PHP behavior)
 type Crc32Hasher struct{}
 
 // Hash returns a signed 32-bit CRC32 hash (matches PHP crc32 behavior)
 func (h *Crc32Hasher) Hash(str string) int {
-	return int(int32(crc32.ChecksumIEEE([]byte(str))))
+	return int(int32(crc32.ChecksumIEEE(stringBytes(str))))
 }
 
 // Md5Hasher uses MD5 to hash values (matches PHP Flexihash MD5 hasher)
@@ -28,39 +47,53 @@ type Md5Hasher struct{}
 
 // Hash returns a 32-bit hash from MD5 (matches PHP Flexihash behavior)
 func (h *Md5Hasher) Hash(str string) int {
-	hash := md5.Sum([]byte(str))
-	hexStr := hex.EncodeToString(hash[:])
-	// Take first 8 characters (32 bits) and convert to int
-	var result int64
-	for i := 0; i < 8 && i < len(hexStr); i++ {
-		result = result*16 + int64(hexDigitToInt(hexStr[i]))
-	}
-	return int(result)
+	hash := md5.Sum(stringBytes(str))
+	// The first 8 hexits are the first 4 bytes, read big-endian
+	return int(binary.BigEndian.Uint32(hash[:4]))
 }
 
-func hexDigitToInt(c byte) int {
-	if c >= '0' && c <= '9' {
-		return int(c - '0')
-	}
-	if c >= 'a' && c <= 'f' {
-		return int(c - 'a' + 10)
-	}
-	if c >= 'A' && c <= 'F' {
-		return int(c - 'A' + 10)
-	}
-	return 0
+// stringBytes returns the bytes of str without copying them, for hash
+// functions that take a byte slice. The result must not be modified.
+func stringBytes(str string) []byte {
+	return unsafe.Slice(unsafe.StringData(str), len(str))
+}
+
+// mix64 is the MurmurHash3 64-bit finalizer, used to derive
+// well-distributed values from a 32-bit hash
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
 }
 
-// FlexiHash implements consistent hashing
+// FlexiHash implements consistent hashing.
+//
+// Changes to the targets are merged into the sorted ring by the next
+// lookup, or up front by Build. A FlexiHash is not safe for concurrent use
+// unless it has been frozen with Freeze.
 type FlexiHash struct {
 	replicas               int
 	hasher                 Hasher
 	targetCount            int
 	positionToTarget       map[int]string
+	positionClaims         map[int][]string // targets sharing a collided position
 	targetToPositions      map[string][]int
 	positionToTargetSorted bool
 	sortedPositions        []int
+	addedPositions         []int // positions claimed since the last build
+	releasedPositions      []int // positions released since the last build
+	rebuildPositions       bool  // sort all positions instead of merging
+	frozen                 bool
 	positionCount          int
+	probes                 int // multi-probe mode when greater than 1
+	ketama                 bool
+	targetToWeight         map[string]float64
+	targetStatus           map[string]TargetStatus // targets that are not up
+	targetZone             map[string]string       // failure domain labels
+	sharedStatus           *sync.Map               // statuses of the owning ConcurrentFlexiHash, if any
 }
 
 // NewFlexiHash creates a new FlexiHash instance with default settings
@@ -68,46 +101,101 @@ func NewFlexiHash() *FlexiHash {
 	return NewFlexiHashWithHasher(nil, 0)
 }
 
-// NewFlexiHashWithHasher creates a FlexiHash with custom hasher and replicas
+// NewFlexiHashWithHasher creates a FlexiHash with custom hasher and replicas.
+// Replicas below 1 use the default of 64.
 func NewFlexiHashWithHasher(hasher Hasher, replicas int) *FlexiHash {
 	if hasher == nil {
 		hasher = &Crc32Hasher{}
 	}
-	if replicas == 0 {
+	if replicas < 1 {
 		replicas = 64
 	}
 	return &FlexiHash{
 		replicas:          replicas,
 		hasher:            hasher,
 		positionToTarget:  make(map[int]string),
+		positionClaims:    make(map[int][]string),
 		targetToPositions: make(map[string][]int),
+		targetToWeight:    make(map[string]float64),
+		targetStatus:      make(map[string]TargetStatus),
+		targetZone:        make(map[string]string),
 	}
 }
 
+// defaultProbes is the number of probes per lookup in multi-probe mode,
+// giving a peak-to-mean load ratio of about 1.05
+const defaultProbes = 21
+
+// NewMultiProbeFlexiHash creates a FlexiHash in multi-probe mode
+// (Appleton and O'Reilly). Each target gets a single position per unit of
+// weight, and each lookup hashes the resource to several probe positions
+// and picks the target closest clockwise to any of them. This gives a
+// balance similar to many replicas with far less memory. 0 probes uses 21.
+func NewMultiProbeFlexiHash(hasher Hasher, probes int) *FlexiHash {
+	if probes == 0 {
+		probes = defaultProbes
+	}
+	fh := NewFlexiHashWithHasher(hasher, 1)
+	fh.probes = probes
+	return fh
+}
+
 // AddTarget adds a target to the hash ring with optional weight
 func (fh *FlexiHash) AddTarget(target string, weight float64) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
 	if weight == 0 {
 		weight = 1
 	}
 	if _, exists := fh.targetToPositions[target]; exists {
 		return errors.New("Target '" + target + "' already exists.")
 	}
+	replicaCount, err := fh.replicaCount(target, weight)
+	if err != nil {
+		return err
+	}
 	fh.targetToPositions[target] = []int{}
+	fh.targetToWeight[target] = weight
+	fh.targetCount++
+
+	if fh.ketama {
+		fh.markRebuild()
+		return nil
+	}
 
 	// Hash the target into multiple positions
-	replicaCount := int(float64(fh.replicas) * weight)
+	positions := make([]int, 0, replicaCount)
 	for i := 0; i < replicaCount; i++ {
 		position := fh.hasher.Hash(target + strconv.Itoa(i))
-		fh.positionToTarget[position] = target
-		fh.targetToPositions[target] = append(fh.targetToPositions[target], position)
-		fh.positionCount++
+		claimPosition(fh.positionToTarget, fh.positionClaims, position, target)
+		positions = append(positions, position)
 	}
-
-	fh.positionToTargetSorted = false
-	fh.targetCount++
+	fh.targetToPositions[target] = positions
+	fh.markUnsorted(positions, nil)
 	return nil
 }
 
+// maxTargetPositions bounds the positions of one target, so an
+// unreasonably large weight cannot exhaust memory
+const maxTargetPositions = 1 << 20
+
+// replicaCount returns the number of positions for a target of weight,
+// or an error if the weight is negative, not finite or too large. Ketama
+// weights only need to fit the single precision arithmetic of the C clients.
+func (fh *FlexiHash) replicaCount(target string, weight float64) (int, error) {
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) ||
+		(!fh.ketama && float64(fh.replicas)*weight > maxTargetPositions) ||
+		(fh.ketama && weight > math.MaxFloat32) {
+		return 0, errors.New("Invalid weight for target '" + target + "'")
+	}
+	count := int(float64(fh.replicas) * weight)
+	if count < 1 && fh.probes > 1 {
+		count = 1
+	}
+	return count, nil
+}
+
 // AddTargets adds multiple targets with optional weight
 func (fh *FlexiHash) AddTargets(targets []string, weight float64) error {
 	if weight == 0 {
@@ -123,21 +211,151 @@ func (fh *FlexiHash) AddTargets(targets []string, weight float64) error {
 
 // RemoveTarget removes a target from the hash ring
 func (fh *FlexiHash) RemoveTarget(target string) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
 	positions, exists := fh.targetToPositions[target]
 	if !exists {
 		return errors.New("Target '" + target + "' does not exist.")
 	}
 
+	delete(fh.targetToPositions, target)
+	delete(fh.targetToWeight, target)
+	delete(fh.targetStatus, target)
+	delete(fh.targetZone, target)
+	fh.targetCount--
+
+	if fh.ketama {
+		fh.markRebuild()
+		return nil
+	}
 	for _, position := range positions {
-		delete(fh.positionToTarget, position)
+		releasePosition(fh.positionToTarget, fh.positionClaims, position, target)
 	}
-	delete(fh.targetToPositions, target)
+	fh.markUnsorted(nil, positions)
+	return nil
+}
 
-	fh.positionToTargetSorted = false
-	fh.targetCount--
+// ReweightTarget changes the weight of an existing target, keeping its
+// status and zone. Replica positions depend only on the target name and
+// index, so only the positions gained or lost by the new weight change owner.
+func (fh *FlexiHash) ReweightTarget(target string, weight float64) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
+	positions, exists := fh.targetToPositions[target]
+	if !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+	if weight == 0 {
+		weight = 1
+	}
+	replicaCount, err := fh.replicaCount(target, weight)
+	if err != nil {
+		return err
+	}
+	fh.targetToWeight[target] = weight
+
+	if fh.ketama {
+		fh.markRebuild()
+		return nil
+	}
+
+	kept := positions[:min(replicaCount, len(positions))]
+	released := positions[len(kept):]
+	next := make([]int, len(kept), replicaCount)
+	copy(next, kept)
+	for i := len(kept); i < replicaCount; i++ {
+		position := fh.hasher.Hash(target + strconv.Itoa(i))
+		claimPosition(fh.positionToTarget, fh.positionClaims, position, target)
+		next = append(next, position)
+	}
+	if len(released) > 0 {
+		for _, position := range released {
+			releasePosition(fh.positionToTarget, fh.positionClaims, position, target)
+		}
+		// A released replica may share its position with a kept one
+		for _, position := range kept {
+			claimPosition(fh.positionToTarget, fh.positionClaims, position, target)
+		}
+	}
+	fh.targetToPositions[target] = next
+	fh.markUnsorted(next[len(kept):], released)
 	return nil
 }
 
+// claimPosition records target as an owner of position.
+// When several targets hash to the same position the greatest target name
+// owns it, so the ring does not depend on the order targets were added in.
+// This matches PHP flexihash (last added wins) when targets are added in
+// ascending order, as with a sorted AddTargets list.
+func claimPosition[P comparable](owners map[P]string, claims map[P][]string, position P, target string) {
+	owner, exists := owners[position]
+	if !exists {
+		owners[position] = target
+		return
+	}
+	if owner == target {
+		return
+	}
+
+	claimants := claims[position]
+	if claimants == nil {
+		claimants = []string{owner}
+	}
+	for _, claimant := range claimants {
+		if claimant == target {
+			return
+		}
+	}
+	// Copy on append, the slice may be shared with a clone
+	claims[position] = append(claimants[:len(claimants):len(claimants)], target)
+	if target > owner {
+		owners[position] = target
+	}
+}
+
+// releasePosition removes target as an owner of position,
+// handing the position to the greatest remaining claimant if any
+func releasePosition[P comparable](owners map[P]string, claims map[P][]string, position P, target string) {
+	claimants, collided := claims[position]
+	if !collided {
+		if owners[position] == target {
+			delete(owners, position)
+		}
+		return
+	}
+
+	remaining := make([]string, 0, len(claimants)-1)
+	for _, claimant := range claimants {
+		if claimant != target {
+			remaining = append(remaining, claimant)
+		}
+	}
+	if len(remaining) == len(claimants) {
+		return
+	}
+
+	owner := remaining[0]
+	for _, claimant := range remaining[1:] {
+		if claimant > owner {
+			owner = claimant
+		}
+	}
+	if len(remaining) == 1 {
+		delete(claims, position)
+	} else {
+		claims[position] = remaining
+	}
+	owners[position] = owner
+}
+
+// Collisions returns the number of positions claimed by more than one target
+func (fh *FlexiHash) Collisions() int {
+	fh.sortPositionTargets()
+	return len(fh.positionClaims)
+}
+
 // GetAllTargets returns a list of all potential targets
 func (fh *FlexiHash) GetAllTargets() []string {
 	var targets []string
@@ -149,103 +367,339 @@ func (fh *FlexiHash) GetAllTargets() []string {
 
 // Lookup finds the target for a given resource
 func (fh *FlexiHash) Lookup(resource string) (string, error) {
-	targets, err := fh.LookupList(resource, 1)
+	return fh.lookup(resource)
+}
+
+// lookup finds the target for a resource without allocating
+func (fh *FlexiHash) lookup(resource string) (string, error) {
+	var buf [1]string
+	targets, err := fh.AppendLookupList(buf[:0], resource, 1)
 	if err != nil {
 		return "", err
 	}
 	if len(targets) == 0 {
-		return "", errors.New("No targets exist")
+		return "", fh.noTargetsError()
 	}
 	return targets[0], nil
 }
 
-// LookupList returns a list of targets for the resource, in order of precedence
+// noTargetsError explains why a lookup found no target
+func (fh *FlexiHash) noTargetsError() error {
+	if fh.targetCount > 0 {
+		return errors.New("No targets are up")
+	}
+	return errors.New("No targets exist")
+}
+
+// LookupList returns a list of targets for the resource, in order of precedence.
+// Targets that are not up are skipped.
 func (fh *FlexiHash) LookupList(resource string, requestedCount int) ([]string, error) {
 	if requestedCount < 1 {
 		return nil, errors.New("Invalid count requested")
 	}
+	results := make([]string, 0, min(requestedCount, fh.targetCount))
+	return fh.AppendLookupList(results, resource, requestedCount)
+}
+
+// AppendLookupList appends the targets for the resource to dst, in order of
+// precedence, and returns the extended slice. With a built-in hasher and
+// enough capacity in dst, it does not allocate.
+func (fh *FlexiHash) AppendLookupList(dst []string, resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return dst, errors.New("Invalid count requested")
+	}
+
+	fh.sortPositionTargets()
 
 	// Handle no targets
-	if len(fh.positionToTarget) == 0 {
-		return []string{}, nil
+	if fh.positionCount == 0 {
+		return dst, nil
 	}
 
 	// Optimize single target
 	if fh.targetCount == 1 {
-		// Return unique targets only
-		result := []string{}
-		seen := make(map[string]bool)
-		for _, target := range fh.positionToTarget {
-			if !seen[target] {
-				result = append(result, target)
-				seen[target] = true
+		for target := range fh.targetToPositions {
+			if fh.isUp(target) {
+				dst = append(dst, target)
 			}
 		}
-		return result, nil
+		return dst, nil
 	}
 
-	// Hash resource to a position
-	resourcePosition := fh.hasher.Hash(resource)
-
-	fh.sortPositionTargets()
-	positions := fh.sortedPositions
+	found := 0
+	fh.walk(fh.lookupPosition(resource), func(target string) bool {
+		if fh.isUp(target) {
+			dst = append(dst, target)
+			found++
+		}
+		return found < requestedCount
+	})
+	return dst, nil
+}
 
-	// Binary search for the first position greater than resource position
-	low := 0
-	high := fh.positionCount - 1
-	probe := 0
+// lookupPosition returns the position to start walking the ring from.
+// In multi-probe mode it is the position closest clockwise to any probe,
+// measuring distance around the 32-bit hash space. The ring must be sorted and non-empty.
+func (fh *FlexiHash) lookupPosition(resource string) int {
+	position := fh.hasher.Hash(resource)
+	if fh.probes < 2 {
+		return position
+	}
 
-	// If resourcePosition is greater than the largest position, we wrap around to 0
-	if resourcePosition > positions[high] {
-		probe = 0
-	} else {
-		// Standard binary search
-		for low <= high {
-			mid := (low + high) / 2
-			if positions[mid] < resourcePosition {
-				low = mid + 1
-			} else {
-				probe = mid
-				high = mid - 1
-			}
+	best := fh.sortedPositions[searchPosition(fh.sortedPositions, position)]
+	bestDistance := uint32(best - position)
+
+	// Build each probe key "<resource>-<i>" in a pooled buffer
+	buf := keyBuffers.Get().(*[]byte)
+	probeKey := append(append((*buf)[:0], resource...), '-')
+	prefix := len(probeKey)
+	for i := 1; i < fh.probes; i++ {
+		probeKey = strconv.AppendInt(probeKey[:prefix], int64(i), 10)
+		probe := fh.hasher.Hash(fh.keyString(probeKey))
+		candidate := fh.sortedPositions[searchPosition(fh.sortedPositions, probe)]
+		if distance := uint32(candidate - probe); distance < bestDistance {
+			best, bestDistance = candidate, distance
 		}
 	}
+	*buf = probeKey
+	keyBuffers.Put(buf)
+	return best
+}
+
+// walk calls fn with each distinct target clockwise from position, stopping
+// when fn returns false or every target has been visited. The ring must be sorted.
+func (fh *FlexiHash) walk(position int, fn func(target string) bool) {
+	if fh.positionCount == 0 {
+		return
+	}
 
-	var uniqueResults []string
-	seen := make(map[string]bool)
+	probe := searchPosition(fh.sortedPositions, position)
+	var seen targetSet
 
 	// Collect targets starting from probe
-	for len(uniqueResults) < requestedCount {
-		target := fh.positionToTarget[positions[probe]]
-		if !seen[target] {
-			uniqueResults = append(uniqueResults, target)
-			seen[target] = true
+	for i := 0; i < fh.positionCount && seen.len() < fh.targetCount; i++ {
+		target := fh.positionToTarget[fh.sortedPositions[probe]]
+		if seen.add(target) {
+			if !fn(target) {
+				return
+			}
 		}
 
 		probe++
 		if probe >= fh.positionCount {
 			probe = 0
 		}
+	}
+}
+
+// targetSet is a set of targets that does not allocate while it is small,
+// since most walks visit only a few targets
+type targetSet struct {
+	few  [8]string
+	n    int
+	many map[string]bool // all targets, once there are more than fit in few
+}
 
-		// If we've collected all available targets, stop
-		if len(uniqueResults) >= fh.targetCount {
-			break
+// add adds target to the set, reporting whether it was not already present
+func (s *targetSet) add(target string) bool {
+	if s.many == nil {
+		for _, t := range s.few[:s.n] {
+			if t == target {
+				return false
+			}
+		}
+		if s.n < len(s.few) {
+			s.few[s.n] = target
+			s.n++
+			return true
+		}
+		s.many = make(map[string]bool, 2*len(s.few))
+		for _, t := range s.few {
+			s.many[t] = true
 		}
 	}
+	if s.many[target] {
+		return false
+	}
+	s.many[target] = true
+	s.n++
+	return true
+}
 
-	return uniqueResults, nil
+// len returns the number of targets in the set
+func (s *targetSet) len() int {
+	return s.n
 }
 
-// sortPositionTargets sorts the internal mapping by position
+// searchPosition returns the index of the first sorted position at or after
+// position, wrapping around to 0. positions must be sorted and non-empty.
+func searchPosition[P cmp.Ordered](positions []P, position P) int {
+	// Binary search for the first position greater than resource position
+	low := 0
+	high := len(positions) - 1
+	probe := 0
+
+	// If position is greater than the largest position, we wrap around to 0
+	if position > positions[high] {
+		return 0
+	}
+
+	// Standard binary search
+	for low <= high {
+		mid := (low + high) / 2
+		if positions[mid] < position {
+			low = mid + 1
+		} else {
+			probe = mid
+			high = mid - 1
+		}
+	}
+	return probe
+}
+
+// clone returns a copy of the ring that can be modified independently,
+// even if the ring is frozen. Position and claim slices are shared since
+// they are never modified in place.
+func (fh *FlexiHash) clone() *FlexiHash {
+	c := *fh
+	c.frozen = false
+	c.addedPositions = slices.Clone(fh.addedPositions)
+	c.releasedPositions = slices.Clone(fh.releasedPositions)
+	c.positionToTarget = make(map[int]string, len(fh.positionToTarget))
+	for position, target := range fh.positionToTarget {
+		c.positionToTarget[position] = target
+	}
+	c.positionClaims = make(map[int][]string, len(fh.positionClaims))
+	for position, claims := range fh.positionClaims {
+		c.positionClaims[position] = claims
+	}
+	c.targetToPositions = make(map[string][]int, len(fh.targetToPositions))
+	for target, positions := range fh.targetToPositions {
+		c.targetToPositions[target] = positions
+	}
+	c.targetToWeight = make(map[string]float64, len(fh.targetToWeight))
+	for target, weight := range fh.targetToWeight {
+		c.targetToWeight[target] = weight
+	}
+	c.targetStatus = make(map[string]TargetStatus, len(fh.targetStatus))
+	for target, status := range fh.targetStatus {
+		c.targetStatus[target] = status
+	}
+	c.targetZone = make(map[string]string, len(fh.targetZone))
+	for target, zone := range fh.targetZone {
+		c.targetZone[target] = zone
+	}
+	return &c
+}
+
+// Build merges the changes made since the last lookup into the sorted
+// ring. Lookups do this lazily; calling Build after changing targets moves
+// the cost off the first lookup.
+func (fh *FlexiHash) Build() {
+	fh.sortPositionTargets()
+}
+
+// Freeze builds the ring and makes it read-only. Later changes return an
+// error, and since lookups on a frozen ring never write, it is safe for
+// concurrent use.
+func (fh *FlexiHash) Freeze() {
+	fh.sortPositionTargets()
+	fh.frozen = true
+}
+
+// markUnsorted records positions claimed and released since the last
+// build. Once they outnumber the ring's positions, sorting from scratch is
+// cheaper than merging them.
+func (fh *FlexiHash) markUnsorted(claimed, released []int) {
+	fh.positionToTargetSorted = false
+	if fh.rebuildPositions {
+		return
+	}
+	fh.addedPositions = append(fh.addedPositions, claimed...)
+	fh.releasedPositions = append(fh.releasedPositions, released...)
+	if len(fh.addedPositions)+len(fh.releasedPositions) > len(fh.sortedPositions)+len(fh.positionToTarget) {
+		fh.markRebuild()
+	}
+}
+
+// markRebuild makes the next build sort all positions
+func (fh *FlexiHash) markRebuild() {
+	fh.positionToTargetSorted = false
+	fh.rebuildPositions = true
+	fh.addedPositions = nil
+	fh.releasedPositions = nil
+}
+
+// sortPositionTargets sorts the internal mapping by position.
+// Positions claimed and released since the last build are merged into the
+// sorted positions in O(R log R + N) for R changed and N total positions.
 func (fh *FlexiHash) sortPositionTargets() {
-	if !fh.positionToTargetSorted {
-		fh.sortedPositions = make([]int, 0, len(fh.positionToTarget))
+	if fh.positionToTargetSorted {
+		return
+	}
+	if fh.rebuildPositions {
+		if fh.ketama {
+			// Ketama points depend on every weight, so they are placed
+			// once per build rather than once per change
+			fh.placeKetamaPoints()
+		}
+		sorted := make([]int, 0, len(fh.positionToTarget))
 		for pos := range fh.positionToTarget {
-			fh.sortedPositions = append(fh.sortedPositions, pos)
+			sorted = append(sorted, pos)
 		}
 		// Sort by position
-		sort.Ints(fh.sortedPositions)
-		fh.positionToTargetSorted = true
-		fh.positionCount = len(fh.sortedPositions)
+		sort.Ints(sorted)
+		fh.sortedPositions = sorted
+	} else {
+		// A position may have been claimed and released again since the
+		// last build, so whether it is on the ring now decides
+		claimed := fh.settledPositions(fh.addedPositions, true)
+		released := fh.settledPositions(fh.releasedPositions, false)
+		fh.sortedPositions = mergePositions(fh.sortedPositions, claimed, released)
+	}
+	fh.addedPositions = nil
+	fh.releasedPositions = nil
+	fh.rebuildPositions = false
+	fh.positionToTargetSorted = true
+	fh.positionCount = len(fh.sortedPositions)
+}
+
+// settledPositions sorts pending in place and returns its distinct
+// positions that are on the ring, or that are not when occupied is false
+func (fh *FlexiHash) settledPositions(pending []int, occupied bool) []int {
+	pending = slices.DeleteFunc(pending, func(position int) bool {
+		_, exists := fh.positionToTarget[position]
+		return exists != occupied
+	})
+	slices.Sort(pending)
+	return slices.Compact(pending)
+}
+
+// mergePositions returns a new sorted slice of positions with claimed
+// merged in and released left out. All three must be sorted and distinct,
+// and positions is not modified since snapshots may share it.
+func mergePositions(positions, claimed, released []int) []int {
+	merged := make([]int, 0, len(positions)+len(claimed))
+	i, j, k := 0, 0, 0
+	for i < len(positions) || j < len(claimed) {
+		var next int
+		if j == len(claimed) || (i < len(positions) && positions[i] < claimed[j]) {
+			next = positions[i]
+			i++
+		} else {
+			if i < len(positions) && positions[i] == claimed[j] {
+				i++
+			}
+			next = claimed[j]
+			j++
+		}
+		for k < len(released) && released[k] < next {
+			k++
+		}
+		if k < len(released) && released[k] == next {
+			continue
+		}
+		merged = append(merged, next)
 	}
+	return merged
 }
@@ -0,0 +1,94 @@
+package flexihash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint identifies a ring configuration.
+// Rings with equal fingerprints place every key the same way.
+type Fingerprint struct {
+	Hasher   string
+	Replicas int
+	Targets  int
+	Sum      [sha256.Size]byte
+}
+
+// String returns a short form for logs, e.g. "crc32/64x3/9f86d081"
+func (f Fingerprint) String() string {
+	return f.Hasher + "/" + strconv.Itoa(f.Replicas) + "x" + strconv.Itoa(f.Targets) + "/" + hex.EncodeToString(f.Sum[:4])
+}
+
+// Hex returns the full digest in hexadecimal
+func (f Fingerprint) Hex() string {
+	return hex.EncodeToString(f.Sum[:])
+}
+
+// Fingerprint returns a deterministic fingerprint of the ring computed from
+// its hasher name, replicas, mode, targets and weights.
+//
+// The digest is the SHA-256 of these lines, each ending in "\n", so other
+// implementations can compute it too:
+//
+//	flexihash-fingerprint-v1
+//	hasher=<name>
+//	replicas=<replicas>
+//	probes=<probes>
+//	ketama=<true|false>
+//	target=<name>\t<weight>    (one per target, sorted by name)
+//
+// Weights use the shortest decimal form that round-trips, e.g. "1" or "2.5".
+// Targets with a zone end in "\t<zone>" after the weight. In names and
+// zones, backslash, tab and newline are escaped as "\\", "\t" and "\n",
+// so different rings cannot produce the same text.
+// It fails if the ring's hasher is not registered.
+func (fh *FlexiHash) Fingerprint() (Fingerprint, error) {
+	return fh.fingerprint(false)
+}
+
+// FingerprintWithPositions returns a fingerprint that also covers every
+// sorted ring position and its owner, as "position=<position>\t<target>"
+// lines after the target lines. It detects rings whose hashers share a
+// name but not an implementation.
+func (fh *FlexiHash) FingerprintWithPositions() (Fingerprint, error) {
+	return fh.fingerprint(true)
+}
+
+// fingerprintEscaper escapes the separators of the canonical text in names
+var fingerprintEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n")
+
+func (fh *FlexiHash) fingerprint(withPositions bool) (Fingerprint, error) {
+	def, err := fh.Definition()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	var b strings.Builder
+	b.WriteString("flexihash-fingerprint-v1\n")
+	b.WriteString("hasher=" + fingerprintEscaper.Replace(def.Hasher) + "\n")
+	b.WriteString("replicas=" + strconv.Itoa(def.Replicas) + "\n")
+	b.WriteString("probes=" + strconv.Itoa(def.Probes) + "\n")
+	b.WriteString("ketama=" + strconv.FormatBool(def.Ketama) + "\n")
+	for _, target := range def.Targets {
+		b.WriteString("target=" + fingerprintEscaper.Replace(target.Name) + "\t" + strconv.FormatFloat(target.Weight, 'g', -1, 64))
+		if target.Zone != "" {
+			b.WriteString("\t" + fingerprintEscaper.Replace(target.Zone))
+		}
+		b.WriteString("\n")
+	}
+	if withPositions {
+		fh.sortPositionTargets()
+		for _, position := range fh.sortedPositions {
+			b.WriteString("position=" + strconv.Itoa(position) + "\t" + fingerprintEscaper.Replace(fh.positionToTarget[position]) + "\n")
+		}
+	}
+
+	return Fingerprint{
+		Hasher:   def.Hasher,
+		Replicas: def.Replicas,
+		Targets:  len(def.Targets),
+		Sum:      sha256.Sum256([]byte(b.String())),
+	}, nil
+}
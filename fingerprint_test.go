@@ -0,0 +1,122 @@
+package flexihash
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintMatchesDocumentedFormat(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTarget("cache-2", 2.5)
+	fh.AddTarget("cache-1", 1)
+
+	fingerprint, err := fh.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+
+	canonical := "flexihash-fingerprint-v1\n" +
+		"hasher=crc32\n" +
+		"replicas=64\n" +
+		"probes=0\n" +
+		"ketama=false\n" +
+		"target=cache-1\t1\n" +
+		"target=cache-2\t2.5\n"
+	if fingerprint.Sum != sha256.Sum256([]byte(canonical)) {
+		t.Errorf("Fingerprint does not match the documented canonical form")
+	}
+	if !strings.HasPrefix(fingerprint.String(), "crc32/64x2/") || len(fingerprint.String()) != len("crc32/64x2/")+8 {
+		t.Errorf("Unexpected short form %s", fingerprint)
+	}
+	if len(fingerprint.Hex()) != 64 {
+		t.Errorf("Expected 64 hex digits, got %s", fingerprint.Hex())
+	}
+}
+
+func TestFingerprintIgnoresInsertionOrder(t *testing.T) {
+	fh1 := NewFlexiHash()
+	fh1.AddTargets([]string{"a", "b", "c"}, 1)
+	fh2 := NewFlexiHash()
+	fh2.AddTargets([]string{"c", "a", "b"}, 1)
+
+	f1, _ := fh1.FingerprintWithPositions()
+	f2, _ := fh2.FingerprintWithPositions()
+	if f1 != f2 {
+		t.Errorf("Expected equal fingerprints, got %s and %s", f1, f2)
+	}
+}
+
+func TestFingerprintDetectsDrift(t *testing.T) {
+	base := NewFlexiHash()
+	base.AddTargets([]string{"a", "b"}, 1)
+	expected, _ := base.Fingerprint()
+
+	variants := map[string]*FlexiHash{
+		"replicas": NewFlexiHashWithHasher(nil, 32),
+		"hasher":   NewFlexiHashWithHasher(&Md5Hasher{}, 0),
+		"probes":   NewMultiProbeFlexiHash(nil, 0),
+	}
+	variants["replicas"].AddTargets([]string{"a", "b"}, 1)
+	variants["hasher"].AddTargets([]string{"a", "b"}, 1)
+	variants["probes"].AddTargets([]string{"a", "b"}, 1)
+
+	weight := NewFlexiHash()
+	weight.AddTarget("a", 1)
+	weight.AddTarget("b", 2)
+	variants["weight"] = weight
+
+	targets := NewFlexiHash()
+	targets.AddTargets([]string{"a", "b", "c"}, 1)
+	variants["targets"] = targets
+
+	for name, fh := range variants {
+		if got, _ := fh.Fingerprint(); got == expected {
+			t.Errorf("%s: expected fingerprint to change", name)
+		}
+	}
+}
+
+func TestFingerprintWithPositions(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"a", "b"}, 1)
+
+	plain, _ := fh.Fingerprint()
+	withPositions, err := fh.FingerprintWithPositions()
+	if err != nil {
+		t.Fatalf("FingerprintWithPositions failed: %v", err)
+	}
+	if plain == withPositions {
+		t.Error("Expected position fingerprint to differ from plain fingerprint")
+	}
+
+	if _, err := NewFlexiHashWithHasher(&MockHasher{}, 1).Fingerprint(); err == nil {
+		t.Error("Expected error for unregistered hasher")
+	}
+}
+
+func TestFingerprintEscapesSeparators(t *testing.T) {
+	split := NewFlexiHash()
+	split.AddTargets([]string{"a", "b"}, 1)
+	joined := NewFlexiHash()
+	joined.AddTarget("a\t1\ntarget=b", 1)
+
+	a, _ := split.Fingerprint()
+	b, _ := joined.Fingerprint()
+	if a.Sum == b.Sum {
+		t.Error("Expected a name containing separators to change the fingerprint")
+	}
+
+	fh := NewFlexiHash()
+	fh.AddTarget("a\tb\\", 1)
+	fingerprint, _ := fh.Fingerprint()
+	canonical := "flexihash-fingerprint-v1\n" +
+		"hasher=crc32\n" +
+		"replicas=64\n" +
+		"probes=0\n" +
+		"ketama=false\n" +
+		"target=a\\tb\\\\\t1\n"
+	if fingerprint.Sum != sha256.Sum256([]byte(canonical)) {
+		t.Error("Fingerprint does not match the documented escaping")
+	}
+}
@@ -0,0 +1,192 @@
+package flexihash
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Hasher64 is the interface for 64-bit hash functions
+type Hasher64 interface {
+	Hash64(string) uint64
+}
+
+// Hasher64From adapts a 32-bit Hasher to Hasher64.
+// Adapted values keep the order of the original signed values, so a
+// FlexiHash64 using an adapted hasher places every key exactly like a
+// FlexiHash with the same hasher, including PHP-compatible CRC32.
+func Hasher64From(hasher Hasher) Hasher64 {
+	return hasherAdapter{hasher: hasher}
+}
+
+type hasherAdapter struct {
+	hasher Hasher
+}
+
+// Hash64 maps the signed hash onto uint64 by flipping the sign bit
+func (a hasherAdapter) Hash64(str string) uint64 {
+	return uint64(int64(a.hasher.Hash(str))) ^ 1<<63
+}
+
+// FlexiHash64 implements consistent hashing with 64-bit ring positions.
+// With a 64-bit hasher, large rings have far fewer position collisions and
+// finer arcs than FlexiHash.
+type FlexiHash64 struct {
+	replicas               int
+	hasher                 Hasher64
+	targetCount            int
+	positionToTarget       map[uint64]string
+	positionClaims         map[uint64][]string // targets sharing a collided position
+	targetToPositions      map[string][]uint64
+	positionToTargetSorted bool
+	sortedPositions        []uint64
+}
+
+var _ Ring = (*FlexiHash64)(nil)
+
+// NewFlexiHash64 creates a new FlexiHash64 instance with default settings.
+// The default hasher is CRC32 adapted to 64 bits, which is PHP compatible.
+func NewFlexiHash64() *FlexiHash64 {
+	return NewFlexiHash64WithHasher(nil, 0)
+}
+
+// NewFlexiHash64WithHasher creates a FlexiHash64 with custom hasher and replicas.
+// Replicas below 1 use the default of 64.
+func NewFlexiHash64WithHasher(hasher Hasher64, replicas int) *FlexiHash64 {
+	if hasher == nil {
+		hasher = Hasher64From(&Crc32Hasher{})
+	}
+	if replicas < 1 {
+		replicas = 64
+	}
+	return &FlexiHash64{
+		replicas:          replicas,
+		hasher:            hasher,
+		positionToTarget:  make(map[uint64]string),
+		positionClaims:    make(map[uint64][]string),
+		targetToPositions: make(map[string][]uint64),
+	}
+}
+
+// AddTarget adds a target to the hash ring with optional weight
+func (fh *FlexiHash64) AddTarget(target string, weight float64) error {
+	if weight == 0 {
+		weight = 1
+	}
+	if _, exists := fh.targetToPositions[target]; exists {
+		return errors.New("Target '" + target + "' already exists.")
+	}
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) || float64(fh.replicas)*weight > maxTargetPositions {
+		return errors.New("Invalid weight for target '" + target + "'")
+	}
+
+	// Hash the target into multiple positions
+	replicaCount := int(float64(fh.replicas) * weight)
+	positions := make([]uint64, 0, replicaCount)
+	for i := 0; i < replicaCount; i++ {
+		position := fh.hasher.Hash64(target + strconv.Itoa(i))
+		claimPosition(fh.positionToTarget, fh.positionClaims, position, target)
+		positions = append(positions, position)
+	}
+	fh.targetToPositions[target] = positions
+
+	fh.positionToTargetSorted = false
+	fh.targetCount++
+	return nil
+}
+
+// AddTargets adds multiple targets with optional weight
+func (fh *FlexiHash64) AddTargets(targets []string, weight float64) error {
+	for _, target := range targets {
+		if err := fh.AddTarget(target, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveTarget removes a target from the hash ring
+func (fh *FlexiHash64) RemoveTarget(target string) error {
+	positions, exists := fh.targetToPositions[target]
+	if !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+
+	for _, position := range positions {
+		releasePosition(fh.positionToTarget, fh.positionClaims, position, target)
+	}
+	delete(fh.targetToPositions, target)
+
+	fh.positionToTargetSorted = false
+	fh.targetCount--
+	return nil
+}
+
+// Collisions returns the number of positions claimed by more than one target
+func (fh *FlexiHash64) Collisions() int {
+	return len(fh.positionClaims)
+}
+
+// GetAllTargets returns a list of all potential targets
+func (fh *FlexiHash64) GetAllTargets() []string {
+	var targets []string
+	for target := range fh.targetToPositions {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// Lookup finds the target for a given resource
+func (fh *FlexiHash64) Lookup(resource string) (string, error) {
+	targets, err := fh.LookupList(resource, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(targets) == 0 {
+		return "", errors.New("No targets exist")
+	}
+	return targets[0], nil
+}
+
+// LookupList returns a list of targets for the resource, in order of precedence
+func (fh *FlexiHash64) LookupList(resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return nil, errors.New("Invalid count requested")
+	}
+	if len(fh.positionToTarget) == 0 {
+		return []string{}, nil
+	}
+
+	fh.sortPositionTargets()
+	positions := fh.sortedPositions
+	probe := searchPosition(positions, fh.hasher.Hash64(resource))
+
+	var uniqueResults []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(positions) && len(uniqueResults) < requestedCount && len(seen) < fh.targetCount; i++ {
+		target := fh.positionToTarget[positions[probe]]
+		if !seen[target] {
+			uniqueResults = append(uniqueResults, target)
+			seen[target] = true
+		}
+
+		probe++
+		if probe >= len(positions) {
+			probe = 0
+		}
+	}
+	return uniqueResults, nil
+}
+
+// sortPositionTargets sorts the internal mapping by position
+func (fh *FlexiHash64) sortPositionTargets() {
+	if !fh.positionToTargetSorted {
+		fh.sortedPositions = make([]uint64, 0, len(fh.positionToTarget))
+		for pos := range fh.positionToTarget {
+			fh.sortedPositions = append(fh.sortedPositions, pos)
+		}
+		slices.Sort(fh.sortedPositions)
+		fh.positionToTargetSorted = true
+	}
+}
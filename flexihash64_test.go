@@ -0,0 +1,123 @@
+package flexihash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// fnv64Hasher is a 64-bit test hasher
+type fnv64Hasher struct{}
+
+func (h fnv64Hasher) Hash64(str string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(str); i++ {
+		hash ^= uint64(str[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+func TestHasher64FromPreservesOrder(t *testing.T) {
+	values := []int{math.MinInt32, -1, 0, 1, math.MaxInt32, math.MaxUint32}
+	for i := 1; i < len(values); i++ {
+		lower := Hasher64From(&MockHasher{hashValue: values[i-1]}).Hash64("")
+		higher := Hasher64From(&MockHasher{hashValue: values[i]}).Hash64("")
+		if lower >= higher {
+			t.Errorf("Adapted %d (%d) should sort before adapted %d (%d)", values[i-1], lower, values[i], higher)
+		}
+	}
+}
+
+func TestFlexiHash64MatchesFlexiHash(t *testing.T) {
+	for _, hasher := range []Hasher{&Crc32Hasher{}, &Md5Hasher{}} {
+		fh := NewFlexiHashWithHasher(hasher, 64)
+		fh64 := NewFlexiHash64WithHasher(Hasher64From(hasher), 64)
+		for i := 1; i <= 10; i++ {
+			fh.AddTarget("cache-"+strconv.Itoa(i), 1)
+			fh64.AddTarget("cache-"+strconv.Itoa(i), 1)
+		}
+		fh.AddTarget("heavy", 2)
+		fh64.AddTarget("heavy", 2)
+
+		for i := 0; i < 1000; i++ {
+			key := "object-" + strconv.Itoa(i)
+			expected, _ := fh.LookupList(key, 3)
+			got, _ := fh64.LookupList(key, 3)
+			for j := range expected {
+				if got[j] != expected[j] {
+					t.Fatalf("%T key %s: expected %v, got %v", hasher, key, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestFlexiHash64WithHasher64(t *testing.T) {
+	var ring Ring = NewFlexiHash64WithHasher(fnv64Hasher{}, 0)
+	if _, err := ring.Lookup("resource"); err == nil {
+		t.Error("Expected error when looking up with no targets")
+	}
+
+	for i := 1; i <= 5; i++ {
+		ring.AddTarget("node-"+strconv.Itoa(i), 1)
+	}
+	if err := ring.AddTarget("node-1", 1); err == nil {
+		t.Error("Expected error when adding duplicate target")
+	}
+
+	targets, err := ring.LookupList("resource", 10)
+	if err != nil {
+		t.Fatalf("LookupList failed: %v", err)
+	}
+	if len(targets) != 5 {
+		t.Errorf("Expected 5 targets, got %v", targets)
+	}
+
+	if err := ring.RemoveTarget(targets[0]); err != nil {
+		t.Fatalf("RemoveTarget failed: %v", err)
+	}
+	if next, _ := ring.Lookup("resource"); next != targets[1] {
+		t.Errorf("Expected %s after removing %s, got %s", targets[1], targets[0], next)
+	}
+}
+
+func TestFlexiHash64Collisions(t *testing.T) {
+	mockHasher := &MockHasher{hashValue: 10}
+	fh := NewFlexiHash64WithHasher(Hasher64From(mockHasher), 1)
+	fh.AddTarget("b", 1)
+	fh.AddTarget("a", 1)
+
+	if fh.Collisions() != 1 {
+		t.Errorf("Expected 1 collision, got %d", fh.Collisions())
+	}
+	if target, _ := fh.Lookup("resource"); target != "b" {
+		t.Errorf("Expected b, got %s", target)
+	}
+	fh.RemoveTarget("b")
+	if target, _ := fh.Lookup("resource"); target != "a" {
+		t.Errorf("Expected a, got %s", target)
+	}
+}
+
+func TestFlexiHash64InvalidWeights(t *testing.T) {
+	fh := NewFlexiHash64()
+	for _, weight := range []float64{-1, math.NaN(), math.Inf(1), 1e12} {
+		if err := fh.AddTarget("t1", weight); err == nil {
+			t.Errorf("Expected error adding with weight %v", weight)
+		}
+	}
+	if len(fh.GetAllTargets()) != 0 {
+		t.Errorf("Expected no targets, got %v", fh.GetAllTargets())
+	}
+}
+
+func TestFlexiHash64NegativeReplicasUseDefault(t *testing.T) {
+	fh := NewFlexiHash64WithHasher(nil, -1)
+	if err := fh.AddTarget("a", 1); err != nil {
+		t.Fatalf("AddTarget failed: %v", err)
+	}
+	if positions := len(fh.targetToPositions["a"]); positions != 64 {
+		t.Errorf("Expected 64 positions, got %d", positions)
+	}
+}
@@ -1,6 +1,11 @@
 package flexihash
 
 import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
 	"testing"
 )
 
@@ -360,6 +365,62 @@ func BenchmarkLookupList(b *testing.B) {
 	}
 }
 
+// benchmarkKeys are built up front so benchmarks measure only lookups
+var benchmarkKeys = func() []string {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = "resource-" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+func benchmarkRing(fh *FlexiHash) *FlexiHash {
+	for i := 0; i < 10; i++ {
+		fh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+	fh.Lookup("sort before timing")
+	return fh
+}
+
+func BenchmarkLookupPrecomputedKeys(b *testing.B) {
+	fh := benchmarkRing(NewFlexiHash())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fh.Lookup(benchmarkKeys[i%len(benchmarkKeys)])
+	}
+}
+
+func BenchmarkAppendLookupList(b *testing.B) {
+	fh := benchmarkRing(NewFlexiHash())
+	dst := make([]string, 0, 3)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst, _ = fh.AppendLookupList(dst[:0], benchmarkKeys[i%len(benchmarkKeys)], 3)
+	}
+}
+
+func BenchmarkAppendLookupListMd5(b *testing.B) {
+	fh := benchmarkRing(NewFlexiHashWithHasher(&Md5Hasher{}, 64))
+	dst := make([]string, 0, 3)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst, _ = fh.AppendLookupList(dst[:0], benchmarkKeys[i%len(benchmarkKeys)], 3)
+	}
+}
+
+func BenchmarkAppendLookupListMultiProbe(b *testing.B) {
+	fh := benchmarkRing(NewMultiProbeFlexiHash(nil, 0))
+	dst := make([]string, 0, 3)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst, _ = fh.AppendLookupList(dst[:0], benchmarkKeys[i%len(benchmarkKeys)], 3)
+	}
+}
+
 // Test custom hashers
 func TestMd5Hasher(t *testing.T) {
 	hasher := &Md5Hasher{}
@@ -447,3 +508,298 @@ func TestCustomHasherInterface(t *testing.T) {
 	}
 }
 
+
+func TestPositionCollisionOwnerIsDeterministic(t *testing.T) {
+	for _, order := range [][]string{{"a", "b"}, {"b", "a"}} {
+		mockHasher := &MockHasher{hashValue: 10}
+		fh := NewFlexiHashWithHasher(mockHasher, 1)
+		for _, target := range order {
+			fh.AddTarget(target, 1)
+		}
+
+		if fh.Collisions() != 1 {
+			t.Errorf("Order %v: expected 1 collision, got %d", order, fh.Collisions())
+		}
+		target, _ := fh.Lookup("resource")
+		if target != "b" {
+			t.Errorf("Order %v: expected b to own the collided position, got %s", order, target)
+		}
+	}
+}
+
+func TestRemoveTargetRestoresCollidedOwner(t *testing.T) {
+	mockHasher := &MockHasher{}
+	fh := NewFlexiHashWithHasher(mockHasher, 1)
+
+	mockHasher.hashValue = 10
+	fh.AddTarget("a", 1)
+	fh.AddTarget("b", 1)
+	mockHasher.hashValue = 20
+	fh.AddTarget("c", 1)
+
+	fh.RemoveTarget("b")
+	if fh.Collisions() != 0 {
+		t.Errorf("Expected 0 collisions after removal, got %d", fh.Collisions())
+	}
+
+	mockHasher.hashValue = 5
+	targets, _ := fh.LookupList("resource", 3)
+	expected := []string{"a", "c"}
+	if len(targets) != len(expected) || targets[0] != expected[0] || targets[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, targets)
+	}
+
+	fh.RemoveTarget("a")
+	targets, _ = fh.LookupList("resource", 3)
+	if len(targets) != 1 || targets[0] != "c" {
+		t.Errorf("Expected [c], got %v", targets)
+	}
+}
+
+func TestReweightTarget(t *testing.T) {
+	fh := NewFlexiHashWithHasher(nil, 8)
+	fh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+
+	if err := fh.ReweightTarget("t2", 2); err != nil {
+		t.Fatalf("ReweightTarget failed: %v", err)
+	}
+	expected := NewFlexiHashWithHasher(nil, 8)
+	expected.AddTarget("t1", 1)
+	expected.AddTarget("t2", 2)
+	expected.AddTarget("t3", 1)
+	for i := 0; i < 200; i++ {
+		key := "key-" + strconv.Itoa(i)
+		want, _ := expected.Lookup(key)
+		got, _ := fh.Lookup(key)
+		if got != want {
+			t.Fatalf("Key %s: expected %s, got %s", key, want, got)
+		}
+	}
+
+	if err := fh.ReweightTarget("t4", 2); err == nil {
+		t.Error("Expected error when reweighting non-existent target")
+	}
+}
+
+func TestReweightTargetKeepsStatusAndZone(t *testing.T) {
+	fh := NewFlexiHashWithHasher(nil, 8)
+	fh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	fh.SetTargetZone("t2", "zone-a")
+	fh.SetTargetStatus("t2", StatusDown)
+
+	for _, weight := range []float64{3, 0.5} {
+		if err := fh.ReweightTarget("t2", weight); err != nil {
+			t.Fatalf("ReweightTarget failed: %v", err)
+		}
+		if zone, _ := fh.Zone("t2"); zone != "zone-a" {
+			t.Errorf("Weight %v: expected zone-a, got %q", weight, zone)
+		}
+		if status, _ := fh.Status("t2"); status != StatusDown {
+			t.Errorf("Weight %v: expected down, got %s", weight, status)
+		}
+
+		expected := NewFlexiHashWithHasher(nil, 8)
+		expected.AddTarget("t1", 1)
+		expected.AddTarget("t2", weight)
+		expected.AddTarget("t3", 1)
+		expected.SetTargetStatus("t2", StatusDown)
+		for i := 0; i < 200; i++ {
+			key := "key-" + strconv.Itoa(i)
+			want, _ := expected.LookupList(key, 3)
+			got, _ := fh.LookupList(key, 3)
+			if !slices.Equal(got, want) {
+				t.Fatalf("Weight %v, key %s: expected %v, got %v", weight, key, want, got)
+			}
+		}
+	}
+}
+
+func TestInvalidWeights(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTarget("t1", 1)
+	for _, weight := range []float64{-1, math.NaN(), math.Inf(1), 1e12} {
+		if err := fh.AddTarget("t2", weight); err == nil {
+			t.Errorf("Expected error adding with weight %v", weight)
+		}
+		if err := fh.ReweightTarget("t1", weight); err == nil {
+			t.Errorf("Expected error reweighting to %v", weight)
+		}
+	}
+	if targets := fh.GetAllTargets(); len(targets) != 1 || fh.targetToWeight["t1"] != 1 {
+		t.Errorf("Expected failed changes to leave the ring alone, got %v", fh.targetToWeight)
+	}
+}
+
+func TestNegativeReplicasUseDefault(t *testing.T) {
+	fh := NewFlexiHashWithHasher(nil, -1)
+	if err := fh.AddTarget("a", 1); err != nil {
+		t.Fatalf("AddTarget failed: %v", err)
+	}
+	if positions := len(fh.targetToPositions["a"]); positions != 64 {
+		t.Errorf("Expected 64 positions, got %d", positions)
+	}
+}
+
+func TestAppendLookupList(t *testing.T) {
+	fh := NewFlexiHash()
+	for i := 0; i < 20; i++ {
+		fh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+
+	for _, key := range benchmarkKeys[:100] {
+		expected, _ := fh.LookupList(key, 20)
+		got, err := fh.AppendLookupList([]string{"existing"}, key, 20)
+		if err != nil {
+			t.Fatalf("AppendLookupList failed: %v", err)
+		}
+		if len(got) != 21 || got[0] != "existing" {
+			t.Fatalf("Key %s: expected dst prefix and 20 targets, got %v", key, got)
+		}
+		seen := make(map[string]bool)
+		for i, target := range got[1:] {
+			if target != expected[i] || seen[target] {
+				t.Fatalf("Key %s: expected %v, got %v", key, expected, got[1:])
+			}
+			seen[target] = true
+		}
+	}
+
+	if _, err := fh.AppendLookupList(nil, "key", 0); err == nil {
+		t.Error("Expected error for invalid count")
+	}
+}
+
+func TestAppendLookupListDoesNotAllocate(t *testing.T) {
+	fh := benchmarkRing(NewFlexiHash())
+	dst := make([]string, 0, 3)
+	allocs := testing.AllocsPerRun(100, func() {
+		dst, _ = fh.AppendLookupList(dst[:0], "resource", 3)
+		fh.Lookup("resource")
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %v", allocs)
+	}
+}
+
+// smallHasher maps everything onto a few positions, so targets collide often
+type smallHasher struct{}
+
+func (h smallHasher) Hash(str string) int {
+	return (&Crc32Hasher{}).Hash(str) % 100
+}
+
+// checkSorted checks that the built ring holds exactly the claimed positions in order
+func checkSorted(t *testing.T, fh *FlexiHash, step int) {
+	t.Helper()
+	expected := make([]int, 0, len(fh.positionToTarget))
+	for position := range fh.positionToTarget {
+		expected = append(expected, position)
+	}
+	sort.Ints(expected)
+	if !slices.Equal(fh.sortedPositions, expected) || fh.positionCount != len(expected) {
+		t.Fatalf("Step %d: expected positions %v, got %v", step, expected, fh.sortedPositions)
+	}
+}
+
+func TestIncrementalBuildMatchesFullSort(t *testing.T) {
+	for name, fh := range map[string]*FlexiHash{
+		"crc32":      NewFlexiHash(),
+		"collisions": NewFlexiHashWithHasher(smallHasher{}, 8),
+		"ketama":     NewKetamaFlexiHash(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			for step := 0; step < 300; step++ {
+				target := "target" + strconv.Itoa(rng.IntN(20))
+				if err := fh.AddTarget(target, float64(rng.IntN(3)+1)); err != nil {
+					fh.RemoveTarget(target)
+				}
+				// Build only sometimes, so changes pile up between builds
+				if rng.IntN(3) == 0 {
+					fh.Build()
+					checkSorted(t, fh, step)
+				}
+			}
+			fh.Build()
+			checkSorted(t, fh, -1)
+		})
+	}
+}
+
+func TestBuildMatchesFreshRing(t *testing.T) {
+	fh := NewFlexiHash()
+	for i := 0; i < 10; i++ {
+		fh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+	fh.Build()
+	fh.RemoveTarget("target3")
+	fh.AddTarget("target10", 2)
+	fh.RemoveTarget("target7")
+	fh.AddTarget("target7", 1)
+	fh.Build()
+
+	fresh := NewFlexiHash()
+	for _, target := range fh.GetAllTargets() {
+		fresh.AddTarget(target, fh.targetToWeight[target])
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		expected, _ := fresh.LookupList(key, 3)
+		got, _ := fh.LookupList(key, 3)
+		if !slices.Equal(got, expected) {
+			t.Fatalf("Key %s: expected %v, got %v", key, expected, got)
+		}
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2"}, 1)
+	fh.Freeze()
+
+	if !fh.positionToTargetSorted {
+		t.Error("Expected Freeze to build the ring")
+	}
+	if err := fh.AddTarget("t3", 1); err == nil {
+		t.Error("Expected error adding to a frozen ring")
+	}
+	if err := fh.RemoveTarget("t1"); err == nil {
+		t.Error("Expected error removing from a frozen ring")
+	}
+	if err := fh.ReweightTarget("t1", 2); err == nil {
+		t.Error("Expected error reweighting a frozen ring")
+	}
+	if err := fh.SetTargetStatus("t1", StatusDown); err == nil {
+		t.Error("Expected error setting status on a frozen ring")
+	}
+	if err := fh.SetTargetZone("t1", "a"); err == nil {
+		t.Error("Expected error setting zone on a frozen ring")
+	}
+	if len(fh.GetAllTargets()) != 2 {
+		t.Errorf("Expected 2 targets, got %v", fh.GetAllTargets())
+	}
+	if _, err := fh.Lookup("resource"); err != nil {
+		t.Errorf("Lookup failed: %v", err)
+	}
+
+	c := fh.clone()
+	if err := c.AddTarget("t3", 1); err != nil {
+		t.Errorf("Expected clone of a frozen ring to be changeable, got %v", err)
+	}
+}
+
+func BenchmarkAddTargetThenLookup(b *testing.B) {
+	fh := NewFlexiHashWithHasher(nil, 160)
+	for i := 0; i < 200; i++ {
+		fh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+	fh.Build()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fh.AddTarget("extra", 1)
+		fh.Lookup("resource")
+		fh.RemoveTarget("extra")
+		fh.Lookup("resource")
+	}
+}
@@ -0,0 +1,309 @@
+// Package flexihttp serves FlexiHash placement decisions over HTTP, so
+// services that cannot embed the Go package get the same answers as those
+// that do.
+//
+// Endpoints:
+//
+//	GET    /lookup?key=K[&n=N]       target for a key, or its N targets in order
+//	POST   /lookup                   batch lookup of {"keys": [...], "n": N}
+//	GET    /targets                  ring definition and fingerprint
+//	POST   /targets                  add {"name": "...", "weight": W}
+//	PUT    /targets/{name}           reweight to {"weight": W}
+//	DELETE /targets/{name}           remove a target
+//	PUT    /targets/{name}/status    set {"status": "up" | "down" | "draining"}
+//
+// Responses are JSON. Errors are reported as {"error": "..."}. The admin
+// endpoints that change the ring require an "Authorization: Bearer <token>"
+// header and are disabled when the handler has no admin token.
+package flexihttp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	flexihash "github.com/mysamimi/flexiHash"
+)
+
+// maxBatchKeys limits the number of keys in one batch lookup
+const maxBatchKeys = 10000
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 4 << 20
+
+// Handler serves lookups against a ConcurrentFlexiHash
+type Handler struct {
+	ring       *flexihash.ConcurrentFlexiHash
+	adminToken string
+	mux        *http.ServeMux
+}
+
+// LookupResult is the placement of one key
+type LookupResult struct {
+	Key     string   `json:"key"`
+	Target  string   `json:"target"`
+	Targets []string `json:"targets,omitempty"`
+}
+
+// BatchRequest is the body of a batch lookup
+type BatchRequest struct {
+	Keys []string `json:"keys"`
+	N    int      `json:"n,omitempty"`
+}
+
+// BatchResponse holds batch results in the order of the requested keys
+type BatchResponse struct {
+	Results     []LookupResult `json:"results"`
+	Fingerprint string         `json:"fingerprint"`
+}
+
+// TargetsResponse describes the current ring
+type TargetsResponse struct {
+	flexihash.RingDefinition
+	Fingerprint string            `json:"fingerprint"`
+	Statuses    map[string]string `json:"statuses,omitempty"` // targets that are not up
+}
+
+// TargetRequest is the body of the add and reweight endpoints
+type TargetRequest struct {
+	Name   string  `json:"name,omitempty"`
+	Weight float64 `json:"weight"`
+}
+
+// StatusRequest is the body of the status endpoint
+type StatusRequest struct {
+	Status string `json:"status"`
+}
+
+// NewHandler creates a Handler for ring. The admin endpoints accept
+// adminToken as a bearer token, or are disabled when it is empty.
+func NewHandler(ring *flexihash.ConcurrentFlexiHash, adminToken string) *Handler {
+	h := &Handler{ring: ring, adminToken: adminToken, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /lookup", h.lookup)
+	h.mux.HandleFunc("POST /lookup", h.batchLookup)
+	h.mux.HandleFunc("GET /targets", h.targets)
+	h.mux.HandleFunc("POST /targets", h.admin(h.addTarget))
+	h.mux.HandleFunc("PUT /targets/{name}", h.admin(h.reweightTarget))
+	h.mux.HandleFunc("DELETE /targets/{name}", h.admin(h.removeTarget))
+	h.mux.HandleFunc("PUT /targets/{name}/status", h.admin(h.setStatus))
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !query.Has("key") {
+		writeError(w, http.StatusBadRequest, errors.New("missing key"))
+		return
+	}
+	count := 0
+	if query.Has("n") {
+		var err error
+		if count, err = strconv.Atoi(query.Get("n")); err != nil || count < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid n"))
+			return
+		}
+	}
+
+	result, err := lookupKey(h.ring.Snapshot(), query.Get("key"), count)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) batchLookup(w http.ResponseWriter, r *http.Request) {
+	var request BatchRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Keys) > maxBatchKeys {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New("too many keys"))
+		return
+	}
+	if request.N < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid n"))
+		return
+	}
+
+	// Answer the whole batch and its fingerprint from one ring
+	ring := h.ring.BatchSnapshot()
+	response := BatchResponse{Results: make([]LookupResult, 0, len(request.Keys))}
+	for _, key := range request.Keys {
+		result, err := lookupKey(ring, key, request.N)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		response.Results = append(response.Results, result)
+	}
+	if fingerprint, err := ring.Fingerprint(); err == nil {
+		response.Fingerprint = fingerprint.String()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) targets(w http.ResponseWriter, r *http.Request) {
+	ring := h.ring.Snapshot()
+	def, err := ring.Definition()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := TargetsResponse{RingDefinition: def}
+	for _, target := range def.Targets {
+		if status, err := ring.Status(target.Name); err == nil && status != flexihash.StatusUp {
+			if response.Statuses == nil {
+				response.Statuses = make(map[string]string)
+			}
+			response.Statuses[target.Name] = status.String()
+		}
+	}
+	if fingerprint, err := ring.Fingerprint(); err == nil {
+		response.Fingerprint = fingerprint.String()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) addTarget(w http.ResponseWriter, r *http.Request) {
+	var request TargetRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing name"))
+		return
+	}
+	if err := h.ring.AddTarget(request.Name, request.Weight); err != nil {
+		status := http.StatusBadRequest
+		if h.hasTarget(request.Name) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	h.targets(w, r)
+}
+
+func (h *Handler) reweightTarget(w http.ResponseWriter, r *http.Request) {
+	var request TargetRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	name := r.PathValue("name")
+	if err := h.ring.ReweightTarget(name, request.Weight); err != nil {
+		writeError(w, h.missingStatus(name), err)
+		return
+	}
+	h.targets(w, r)
+}
+
+func (h *Handler) removeTarget(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.ring.RemoveTarget(name); err != nil {
+		writeError(w, h.missingStatus(name), err)
+		return
+	}
+	h.targets(w, r)
+}
+
+func (h *Handler) setStatus(w http.ResponseWriter, r *http.Request) {
+	var request StatusRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	status, err := flexihash.ParseTargetStatus(request.Status)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	name := r.PathValue("name")
+	if err := h.ring.SetTargetStatus(name, status); err != nil {
+		writeError(w, h.missingStatus(name), err)
+		return
+	}
+	h.targets(w, r)
+}
+
+func (h *Handler) hasTarget(target string) bool {
+	return slices.Contains(h.ring.GetAllTargets(), target)
+}
+
+// missingStatus picks the status for a failed change to an existing target
+func (h *Handler) missingStatus(target string) int {
+	if h.hasTarget(target) {
+		return http.StatusBadRequest
+	}
+	return http.StatusNotFound
+}
+
+// admin wraps an endpoint that changes the ring with the token check
+func (h *Handler) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.adminToken == "" {
+			writeError(w, http.StatusForbidden, errors.New("admin endpoints are disabled"))
+			return
+		}
+		token := []byte("Bearer " + h.adminToken)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), token) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// lookupKey places one key, with its n targets when n is positive
+func lookupKey(ring *flexihash.FlexiHash, key string, n int) (LookupResult, error) {
+	result := LookupResult{Key: key}
+	if n == 0 {
+		target, err := ring.Lookup(key)
+		result.Target = target
+		return result, err
+	}
+
+	targets, err := ring.LookupList(key, n)
+	if err != nil {
+		return result, err
+	}
+	if len(targets) == 0 {
+		// Lookup explains why there is no target
+		_, err := ring.Lookup(key)
+		return result, err
+	}
+	result.Target = targets[0]
+	result.Targets = targets
+	return result, nil
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return errors.New("invalid request body: " + err.Error())
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,230 @@
+package flexihttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	flexihash "github.com/mysamimi/flexiHash"
+)
+
+func newTestHandler(token string) (*Handler, *flexihash.ConcurrentFlexiHash) {
+	ring := flexihash.NewConcurrentFlexiHash()
+	ring.AddTargets([]string{"cache-1", "cache-2", "cache-3"}, 1)
+	return NewHandler(ring, token), ring
+}
+
+func serve(h http.Handler, method, target, body, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func TestLookup(t *testing.T) {
+	h, ring := newTestHandler("")
+
+	w := serve(h, "GET", "/lookup?key=object-a", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	expected, _ := ring.Lookup("object-a")
+	if result := decode[LookupResult](t, w); result.Key != "object-a" || result.Target != expected || result.Targets != nil {
+		t.Errorf("Expected target %s, got %+v", expected, result)
+	}
+
+	w = serve(h, "GET", "/lookup?key=object-a&n=2", "", "")
+	expectedList, _ := ring.LookupList("object-a", 2)
+	result := decode[LookupResult](t, w)
+	if result.Target != expectedList[0] || len(result.Targets) != 2 || result.Targets[1] != expectedList[1] {
+		t.Errorf("Expected targets %v, got %+v", expectedList, result)
+	}
+
+	for _, target := range []string{"/lookup", "/lookup?key=a&n=0", "/lookup?key=a&n=x"} {
+		if w := serve(h, "GET", target, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestLookupEmptyRing(t *testing.T) {
+	h := NewHandler(flexihash.NewConcurrentFlexiHash(), "")
+	for _, target := range []string{"/lookup?key=a", "/lookup?key=a&n=2"} {
+		w := serve(h, "GET", target, "", "")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503, got %d", target, w.Code)
+		}
+		if response := decode[map[string]string](t, w); response["error"] == "" {
+			t.Errorf("%s: expected an error message, got %q", target, w.Body.String())
+		}
+	}
+}
+
+func TestBatchLookup(t *testing.T) {
+	h, ring := newTestHandler("")
+
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	body, _ := json.Marshal(BatchRequest{Keys: keys, N: 2})
+	w := serve(h, "POST", "/lookup", string(body), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	response := decode[BatchResponse](t, w)
+	if len(response.Results) != len(keys) {
+		t.Fatalf("Expected %d results, got %d", len(keys), len(response.Results))
+	}
+	for i, result := range response.Results {
+		expected, _ := ring.LookupList(keys[i], 2)
+		if result.Key != keys[i] || result.Target != expected[0] || result.Targets[1] != expected[1] {
+			t.Errorf("Key %s: expected %v, got %+v", keys[i], expected, result)
+		}
+	}
+	fingerprint, _ := ring.Snapshot().Fingerprint()
+	if response.Fingerprint != fingerprint.String() {
+		t.Errorf("Expected fingerprint %s, got %s", fingerprint, response.Fingerprint)
+	}
+
+	for _, invalid := range []string{"", "{", `{"keys":["a"],"n":-1}`, `{"key":"a"}`} {
+		if w := serve(h, "POST", "/lookup", invalid, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", invalid, w.Code)
+		}
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	disabled, _ := newTestHandler("")
+	if w := serve(disabled, "DELETE", "/targets/cache-1", "", "anything"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 with admin disabled, got %d", w.Code)
+	}
+
+	h, ring := newTestHandler("secret")
+	if w := serve(h, "DELETE", "/targets/cache-1", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", w.Code)
+	}
+	if w := serve(h, "DELETE", "/targets/cache-1", "", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", w.Code)
+	}
+	if len(ring.GetAllTargets()) != 3 {
+		t.Errorf("Expected ring unchanged, got %v", ring.GetAllTargets())
+	}
+}
+
+func TestAdminChangesRing(t *testing.T) {
+	h, ring := newTestHandler("secret")
+
+	w := serve(h, "POST", "/targets", `{"name":"cache-4","weight":2}`, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Add: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	response := decode[TargetsResponse](t, w)
+	if len(response.Targets) != 4 || response.Targets[3] != (flexihash.TargetDefinition{Name: "cache-4", Weight: 2}) {
+		t.Errorf("Expected cache-4 with weight 2, got %+v", response.Targets)
+	}
+
+	if w := serve(h, "PUT", "/targets/cache-4", `{"weight":3}`, "secret"); w.Code != http.StatusOK {
+		t.Errorf("Reweight: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(h, "DELETE", "/targets/cache-1", "", "secret"); w.Code != http.StatusOK {
+		t.Errorf("Remove: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	expected := flexihash.NewFlexiHash()
+	expected.AddTargets([]string{"cache-2", "cache-3"}, 1)
+	expected.AddTarget("cache-4", 3)
+	got, _ := ring.Snapshot().Fingerprint()
+	want, _ := expected.Fingerprint()
+	if got != want {
+		t.Errorf("Expected ring %s, got %s", want, got)
+	}
+
+	w = serve(h, "GET", "/targets", "", "")
+	if response := decode[TargetsResponse](t, w); response.Fingerprint != want.String() || response.Hasher != "crc32" {
+		t.Errorf("Expected fingerprint %s, got %+v", want, response)
+	}
+}
+
+func TestAdminErrors(t *testing.T) {
+	h, _ := newTestHandler("secret")
+	cases := []struct {
+		method, target, body string
+		status               int
+	}{
+		{"POST", "/targets", `{"name":"cache-1"}`, http.StatusConflict},
+		{"POST", "/targets", `{"weight":1}`, http.StatusBadRequest},
+		{"PUT", "/targets/missing", `{"weight":2}`, http.StatusNotFound},
+		{"PUT", "/targets/cache-1", `{"weight":"heavy"}`, http.StatusBadRequest},
+		{"POST", "/targets", `{"name":"cache-4","weight":-1}`, http.StatusBadRequest},
+		{"POST", "/targets", `{"name":"cache-4","weight":1e12}`, http.StatusBadRequest},
+		{"PUT", "/targets/cache-1", `{"weight":-2}`, http.StatusBadRequest},
+		{"PUT", "/targets/cache-1", `{"weight":1e12}`, http.StatusBadRequest},
+		{"DELETE", "/targets/missing", "", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if w := serve(h, c.method, c.target, c.body, "secret"); w.Code != c.status {
+			t.Errorf("%s %s %s: expected %d, got %d", c.method, c.target, c.body, c.status, w.Code)
+		}
+	}
+}
+
+func TestReweightKeepsZone(t *testing.T) {
+	h, ring := newTestHandler("secret")
+	ring.SetTargetZone("cache-1", "zone-a")
+
+	if w := serve(h, "PUT", "/targets/cache-1", `{"weight":2}`, "secret"); w.Code != http.StatusOK {
+		t.Fatalf("Reweight: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if zone, _ := ring.Snapshot().Zone("cache-1"); zone != "zone-a" {
+		t.Errorf("Expected zone-a after reweight, got %q", zone)
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	h, ring := newTestHandler("secret")
+
+	w := serve(h, "PUT", "/targets/cache-1/status", `{"status":"down"}`, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if response := decode[TargetsResponse](t, w); response.Statuses["cache-1"] != "down" || len(response.Statuses) != 1 {
+		t.Errorf("Expected cache-1 down, got %v", response.Statuses)
+	}
+	for i := 0; i < 100; i++ {
+		w := serve(h, "GET", "/lookup?key=key-"+strconv.Itoa(i)+"&n=3", "", "")
+		if result := decode[LookupResult](t, w); result.Target == "cache-1" || len(result.Targets) != 2 {
+			t.Fatalf("Expected cache-1 to be skipped, got %+v", result)
+		}
+	}
+
+	if w := serve(h, "PUT", "/targets/cache-1/status", `{"status":"sideways"}`, "secret"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown status, got %d", w.Code)
+	}
+	if w := serve(h, "PUT", "/targets/missing/status", `{"status":"down"}`, "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing target, got %d", w.Code)
+	}
+
+	ring.SetTargetStatus("cache-2", flexihash.StatusDown)
+	ring.SetTargetStatus("cache-3", flexihash.StatusDraining)
+	w = serve(h, "GET", "/lookup?key=a", "", "")
+	if response := decode[map[string]string](t, w); w.Code != http.StatusServiceUnavailable || response["error"] != "No targets are up" {
+		t.Errorf("Expected 503 with no targets up, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,342 @@
+package flexihash
+
+import (
+	"math/bits"
+)
+
+// The hashers in this file are pure Go implementations of widely used
+// non-cryptographic hash functions, so rings can match hashing done by
+// services in other languages. 32-bit hashers return signed values like
+// Crc32Hasher. 64-bit hashers also implement Hasher64; their Hash returns
+// the high 32 bits as a signed value, use Hash64 with FlexiHash64 for the
+// full width.
+
+// XxHash64Hasher uses xxHash64 with an optional seed
+type XxHash64Hasher struct {
+	Seed uint64
+}
+
+// Murmur3Hasher uses 32-bit MurmurHash3 (x86_32) with an optional seed
+type Murmur3Hasher struct {
+	Seed uint32
+}
+
+// Murmur3x128Hasher uses 128-bit MurmurHash3 (x64_128) with an optional seed
+type Murmur3x128Hasher struct {
+	Seed uint64
+}
+
+// Fnv1a32Hasher uses 32-bit FNV-1a
+type Fnv1a32Hasher struct{}
+
+// Fnv1a64Hasher uses 64-bit FNV-1a
+type Fnv1a64Hasher struct{}
+
+// SipHasher uses keyed SipHash-2-4.
+// The key is read as two little-endian 64-bit words, as in the reference implementation.
+type SipHasher struct {
+	Key [16]byte
+}
+
+var (
+	_ Hasher64 = (*XxHash64Hasher)(nil)
+	_ Hasher64 = (*Murmur3x128Hasher)(nil)
+	_ Hasher64 = (*Fnv1a64Hasher)(nil)
+	_ Hasher64 = (*SipHasher)(nil)
+)
+
+// Hash returns the high 32 bits of the xxHash64 value
+func (h *XxHash64Hasher) Hash(str string) int {
+	return high32(h.Hash64(str))
+}
+
+// Hash64 returns the xxHash64 value
+func (h *XxHash64Hasher) Hash64(str string) uint64 {
+	const (
+		prime1 uint64 = 11400714785074694791
+		prime2 uint64 = 14029467366897019727
+		prime3 uint64 = 1609587929392839161
+		prime4 uint64 = 9650029242287828579
+		prime5 uint64 = 2870177450012600261
+	)
+	round := func(acc, input uint64) uint64 {
+		acc += input * prime2
+		return bits.RotateLeft64(acc, 31) * prime1
+	}
+	mergeRound := func(acc, val uint64) uint64 {
+		acc ^= round(0, val)
+		return acc*prime1 + prime4
+	}
+
+	n := len(str)
+	var hash uint64
+	if n >= 32 {
+		v1 := h.Seed + prime1 + prime2
+		v2 := h.Seed + prime2
+		v3 := h.Seed
+		v4 := h.Seed - prime1
+		for len(str) >= 32 {
+			v1 = round(v1, le64(str[0:8]))
+			v2 = round(v2, le64(str[8:16]))
+			v3 = round(v3, le64(str[16:24]))
+			v4 = round(v4, le64(str[24:32]))
+			str = str[32:]
+		}
+		hash = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		hash = mergeRound(hash, v1)
+		hash = mergeRound(hash, v2)
+		hash = mergeRound(hash, v3)
+		hash = mergeRound(hash, v4)
+	} else {
+		hash = h.Seed + prime5
+	}
+	hash += uint64(n)
+
+	for len(str) >= 8 {
+		hash ^= round(0, le64(str[0:8]))
+		hash = bits.RotateLeft64(hash, 27)*prime1 + prime4
+		str = str[8:]
+	}
+	if len(str) >= 4 {
+		hash ^= uint64(le32(str[0:4])) * prime1
+		hash = bits.RotateLeft64(hash, 23)*prime2 + prime3
+		str = str[4:]
+	}
+	for i := 0; i < len(str); i++ {
+		hash ^= uint64(str[i]) * prime5
+		hash = bits.RotateLeft64(hash, 11) * prime1
+	}
+
+	hash ^= hash >> 33
+	hash *= prime2
+	hash ^= hash >> 29
+	hash *= prime3
+	hash ^= hash >> 32
+	return hash
+}
+
+// Hash returns the signed 32-bit MurmurHash3 value
+func (h *Murmur3Hasher) Hash(str string) int {
+	return int(int32(h.Sum32(str)))
+}
+
+// Sum32 returns the unsigned 32-bit MurmurHash3 value
+func (h *Murmur3Hasher) Sum32(str string) uint32 {
+	const (
+		c1 uint32 = 0xcc9e2d51
+		c2 uint32 = 0x1b873593
+	)
+	n := len(str)
+	hash := h.Seed
+	for len(str) >= 4 {
+		k := le32(str[0:4])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		hash ^= k
+		hash = bits.RotateLeft32(hash, 13)
+		hash = hash*5 + 0xe6546b64
+		str = str[4:]
+	}
+
+	var k uint32
+	switch len(str) {
+	case 3:
+		k ^= uint32(str[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(str[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(str[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		hash ^= k
+	}
+
+	hash ^= uint32(n)
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+	return hash
+}
+
+// Hash returns the high 32 bits of the first 64-bit half of the MurmurHash3 value
+func (h *Murmur3x128Hasher) Hash(str string) int {
+	return high32(h.Hash64(str))
+}
+
+// Hash64 returns the first 64-bit half of the MurmurHash3 value
+func (h *Murmur3x128Hasher) Hash64(str string) uint64 {
+	h1, _ := h.Sum128(str)
+	return h1
+}
+
+// Sum128 returns both 64-bit halves of the MurmurHash3 value
+func (h *Murmur3x128Hasher) Sum128(str string) (uint64, uint64) {
+	const (
+		c1 uint64 = 0x87c37b91114253d5
+		c2 uint64 = 0x4cf5ad432745937f
+	)
+	n := len(str)
+	h1, h2 := h.Seed, h.Seed
+	for len(str) >= 16 {
+		k1 := le64(str[0:8])
+		k2 := le64(str[8:16])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+		str = str[16:]
+	}
+
+	var k1, k2 uint64
+	for i := len(str) - 1; i >= 8; i-- {
+		k2 ^= uint64(str[i]) << (8 * (i - 8))
+	}
+	if len(str) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	for i := min(len(str), 8) - 1; i >= 0; i-- {
+		k1 ^= uint64(str[i]) << (8 * i)
+	}
+	if len(str) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = mix64(h1)
+	h2 = mix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+// Hash returns the signed 32-bit FNV-1a value
+func (h *Fnv1a32Hasher) Hash(str string) int {
+	return int(int32(h.Sum32(str)))
+}
+
+// Sum32 returns the unsigned 32-bit FNV-1a value
+func (h *Fnv1a32Hasher) Sum32(str string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(str); i++ {
+		hash ^= uint32(str[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// Hash returns the high 32 bits of the 64-bit FNV-1a value
+func (h *Fnv1a64Hasher) Hash(str string) int {
+	return high32(h.Hash64(str))
+}
+
+// Hash64 returns the 64-bit FNV-1a value
+func (h *Fnv1a64Hasher) Hash64(str string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(str); i++ {
+		hash ^= uint64(str[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// Hash returns the high 32 bits of the SipHash-2-4 value
+func (h *SipHasher) Hash(str string) int {
+	return high32(h.Hash64(str))
+}
+
+// Hash64 returns the SipHash-2-4 value
+func (h *SipHasher) Hash64(str string) uint64 {
+	k0 := le64(string(h.Key[0:8]))
+	k1 := le64(string(h.Key[8:16]))
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(str)
+	for len(str) >= 8 {
+		m := le64(str[0:8])
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+		str = str[8:]
+	}
+
+	// The final block holds the remaining bytes and the length in its top byte
+	m := uint64(n) << 56
+	for i := len(str) - 1; i >= 0; i-- {
+		m |= uint64(str[i]) << (8 * i)
+	}
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// high32 returns the high 32 bits of a 64-bit hash as a signed value
+func high32(hash uint64) int {
+	return int(int32(hash >> 32))
+}
+
+// le32 reads a little-endian uint32 from the first 4 bytes of str
+func le32(str string) uint32 {
+	return uint32(str[0]) | uint32(str[1])<<8 | uint32(str[2])<<16 | uint32(str[3])<<24
+}
+
+// le64 reads a little-endian uint64 from the first 8 bytes of str
+func le64(str string) uint64 {
+	return uint64(le32(str)) | uint64(le32(str[4:]))<<32
+}
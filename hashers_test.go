@@ -0,0 +1,153 @@
+package flexihash
+
+import (
+	"hash/fnv"
+	"strconv"
+	"testing"
+)
+
+func TestXxHash64Vectors(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+	}
+
+	hasher := &XxHash64Hasher{}
+	for _, tc := range testCases {
+		if got := hasher.Hash64(tc.input); got != tc.expected {
+			t.Errorf("xxHash64(%q): expected %x, got %x", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestMurmur3Vectors(t *testing.T) {
+	testCases := []struct {
+		input    string
+		seed     uint32
+		expected uint32
+	}{
+		{"", 0, 0},
+		{"", 1, 0x514e28b7},
+		{"hello", 0, 0x248bfa47},
+		{"Hello, world!", 0x9747b28c, 0x24884cba},
+		{"The quick brown fox jumps over the lazy dog", 0x9747b28c, 0x2fa826cd},
+	}
+
+	for _, tc := range testCases {
+		hasher := &Murmur3Hasher{Seed: tc.seed}
+		if got := hasher.Sum32(tc.input); got != tc.expected {
+			t.Errorf("murmur3_32(%q, %x): expected %x, got %x", tc.input, tc.seed, tc.expected, got)
+		}
+		if got := hasher.Hash(tc.input); got != int(int32(tc.expected)) {
+			t.Errorf("Hash(%q) should be the signed Sum32 value, got %d", tc.input, got)
+		}
+	}
+}
+
+func TestMurmur3x128Vectors(t *testing.T) {
+	testCases := []struct {
+		input  string
+		h1, h2 uint64
+	}{
+		{"", 0, 0},
+		{"hello", 0xcbd8a7b341bd9b02, 0x5b1e906a48ae1d19},
+		{"The quick brown fox jumps over the lazy dog", 0xe34bbc7bbc071b6c, 0x7a433ca9c49a9347},
+	}
+
+	hasher := &Murmur3x128Hasher{}
+	for _, tc := range testCases {
+		h1, h2 := hasher.Sum128(tc.input)
+		if h1 != tc.h1 || h2 != tc.h2 {
+			t.Errorf("murmur3_x64_128(%q): expected %x %x, got %x %x", tc.input, tc.h1, tc.h2, h1, h2)
+		}
+	}
+}
+
+func TestFnv1aVectors(t *testing.T) {
+	testCases := []struct {
+		input      string
+		expected32 uint32
+		expected64 uint64
+	}{
+		{"", 0x811c9dc5, 0xcbf29ce484222325},
+		{"a", 0xe40c292c, 0xaf63dc4c8601ec8c},
+		{"foobar", 0xbf9cf968, 0x85944171f73967e8},
+	}
+
+	for _, tc := range testCases {
+		if got := (&Fnv1a32Hasher{}).Sum32(tc.input); got != tc.expected32 {
+			t.Errorf("fnv1a32(%q): expected %x, got %x", tc.input, tc.expected32, got)
+		}
+		if got := (&Fnv1a64Hasher{}).Hash64(tc.input); got != tc.expected64 {
+			t.Errorf("fnv1a64(%q): expected %x, got %x", tc.input, tc.expected64, got)
+		}
+	}
+
+	// Cross-check against the standard library
+	for i := 0; i < 100; i++ {
+		input := "key-" + strconv.Itoa(i)
+		h32, h64 := fnv.New32a(), fnv.New64a()
+		h32.Write([]byte(input))
+		h64.Write([]byte(input))
+		if (&Fnv1a32Hasher{}).Sum32(input) != h32.Sum32() || (&Fnv1a64Hasher{}).Hash64(input) != h64.Sum64() {
+			t.Fatalf("FNV-1a of %q differs from hash/fnv", input)
+		}
+	}
+}
+
+// Vectors from the SipHash paper: key 00..0f, message 00..(n-1)
+func TestSipHashVectors(t *testing.T) {
+	hasher := &SipHasher{}
+	for i := range hasher.Key {
+		hasher.Key[i] = byte(i)
+	}
+	message := make([]byte, 15)
+	for i := range message {
+		message[i] = byte(i)
+	}
+
+	testCases := []struct {
+		length   int
+		expected uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{15, 0xa129ca6149be45e5},
+	}
+	for _, tc := range testCases {
+		if got := hasher.Hash64(string(message[:tc.length])); got != tc.expected {
+			t.Errorf("siphash24 of %d bytes: expected %x, got %x", tc.length, tc.expected, got)
+		}
+	}
+}
+
+func TestBuiltInHashersWithRing(t *testing.T) {
+	hashers := []Hasher{
+		&XxHash64Hasher{},
+		&Murmur3Hasher{},
+		&Murmur3x128Hasher{},
+		&Fnv1a32Hasher{},
+		&Fnv1a64Hasher{},
+		&SipHasher{},
+	}
+	for _, hasher := range hashers {
+		fh := NewFlexiHashWithHasher(hasher, 64)
+		fh.AddTargets([]string{"server-1", "server-2", "server-3"}, 1)
+
+		counts := make(map[string]int)
+		for i := 0; i < 3000; i++ {
+			target, err := fh.Lookup("key-" + strconv.Itoa(i))
+			if err != nil {
+				t.Fatalf("%T: Lookup failed: %v", hasher, err)
+			}
+			counts[target]++
+		}
+		if len(counts) != 3 {
+			t.Errorf("%T: expected keys on all 3 targets, got %v", hasher, counts)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package flexihash
+
+import (
+	"errors"
+	"slices"
+	"sort"
+)
+
+// Level is a level of a Hierarchy
+type Level int
+
+const (
+	// LevelAny is the whole hierarchy
+	LevelAny Level = iota
+	// LevelRegion is the level of regions
+	LevelRegion
+	// LevelZone is the level of zones within a region
+	LevelZone
+	// LevelNode is the level of nodes within a zone
+	LevelNode
+)
+
+// PlacementRule describes where Place puts the replicas of a key
+type PlacementRule struct {
+	// Replicas is the number of nodes to place the key on
+	Replicas int
+	// Within confines the replicas to the key's home domain at this level,
+	// e.g. LevelRegion keeps them in the region the key maps to.
+	// LevelAny allows the whole hierarchy.
+	Within Level
+	// Distinct requires each replica in a different domain at this level.
+	// It must be below Within; LevelAny means LevelNode.
+	Distinct Level
+}
+
+// Hierarchy places keys on nodes in the CRUSH style: a key is first placed
+// on a region, then on a zone inside that region, then on a node inside that
+// zone. Regions and zones are picked by weighted rendezvous hashing on the
+// total node weight beneath them, so their shares follow those totals
+// exactly however large they grow, and nodes by a FlexiHash per zone.
+// Adding or removing a node only moves keys into or out of that node's
+// region and zone.
+//
+// A Hierarchy is not safe for concurrent use.
+type Hierarchy struct {
+	hasher   Hasher
+	replicas int
+	root     *hierarchyBucket     // buckets for regions
+	nodes    map[string][2]string // node to region and zone
+}
+
+// hierarchyBucket is a region, zone or node of a Hierarchy
+type hierarchyBucket struct {
+	weight   float64 // node weight, or the total beneath a region or zone
+	ring     Ring    // children by weight, nil for nodes
+	children map[string]*hierarchyBucket
+}
+
+// NewHierarchy creates a Hierarchy with default settings
+func NewHierarchy() *Hierarchy {
+	return NewHierarchyWithHasher(nil, 0)
+}
+
+// NewHierarchyWithHasher creates a Hierarchy whose levels use the given
+// hasher, with replicas per unit of node weight in each zone
+func NewHierarchyWithHasher(hasher Hasher, replicas int) *Hierarchy {
+	h := &Hierarchy{hasher: hasher, replicas: replicas, nodes: make(map[string][2]string)}
+	h.root = newHierarchyBucket(NewRendezvousWithHasher(hasher))
+	return h
+}
+
+func newHierarchyBucket(ring Ring) *hierarchyBucket {
+	return &hierarchyBucket{ring: ring, children: make(map[string]*hierarchyBucket)}
+}
+
+// AddNode adds a node to a zone of a region with optional weight,
+// creating the region and zone if needed. Node names must be unique
+// across the hierarchy.
+func (h *Hierarchy) AddNode(region, zone, node string, weight float64) error {
+	if weight == 0 {
+		weight = 1
+	}
+	if _, exists := h.nodes[node]; exists {
+		return errors.New("Node '" + node + "' already exists.")
+	}
+
+	// New buckets are linked in only once the zone ring accepts the node,
+	// so a rejected weight leaves the hierarchy unchanged
+	regionBucket, regionExists := h.root.children[region]
+	if !regionExists {
+		regionBucket = newHierarchyBucket(NewRendezvousWithHasher(h.hasher))
+	}
+	zoneBucket, zoneExists := regionBucket.children[zone]
+	if !zoneExists {
+		zoneBucket = newHierarchyBucket(NewFlexiHashWithHasher(h.hasher, h.replicas))
+	}
+	if err := zoneBucket.ring.AddTarget(node, weight); err != nil {
+		return err
+	}
+	if !regionExists {
+		h.root.children[region] = regionBucket
+	}
+	if !zoneExists {
+		regionBucket.children[zone] = zoneBucket
+	}
+	zoneBucket.children[node] = &hierarchyBucket{weight: weight}
+	h.nodes[node] = [2]string{region, zone}
+
+	if err := regionBucket.reweight(zone); err != nil {
+		return err
+	}
+	return h.root.reweight(region)
+}
+
+// RemoveNode removes a node, and its zone and region if they become empty
+func (h *Hierarchy) RemoveNode(node string) error {
+	location, exists := h.nodes[node]
+	if !exists {
+		return errors.New("Node '" + node + "' does not exist.")
+	}
+	region, zone := location[0], location[1]
+	regionBucket := h.root.children[region]
+	zoneBucket := regionBucket.children[zone]
+
+	if err := zoneBucket.ring.RemoveTarget(node); err != nil {
+		return err
+	}
+	delete(h.nodes, node)
+	delete(zoneBucket.children, node)
+	if len(zoneBucket.children) == 0 {
+		delete(regionBucket.children, zone)
+	}
+	if err := regionBucket.reweight(zone); err != nil {
+		return err
+	}
+	if len(regionBucket.children) == 0 {
+		delete(h.root.children, region)
+	}
+	return h.root.reweight(region)
+}
+
+// reweight updates the weight of child after a node beneath it changed,
+// removing it from the ring if it no longer exists. Rendezvous scores do not
+// depend on the other targets, so re-adding child only moves its own keys.
+func (b *hierarchyBucket) reweight(child string) error {
+	if slices.Contains(b.ring.GetAllTargets(), child) {
+		if err := b.ring.RemoveTarget(child); err != nil {
+			return err
+		}
+	}
+	childBucket, exists := b.children[child]
+	if !exists {
+		return nil
+	}
+	childBucket.weight = childBucket.totalWeight()
+	return b.ring.AddTarget(child, childBucket.weight)
+}
+
+// totalWeight sums the weights of the children in name order,
+// so the result does not depend on map iteration order
+func (b *hierarchyBucket) totalWeight() float64 {
+	names := make([]string, 0, len(b.children))
+	for name := range b.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	total := 0.0
+	for _, name := range names {
+		total += b.children[name].weight
+	}
+	return total
+}
+
+// Location returns the region and zone of a node
+func (h *Hierarchy) Location(node string) (region, zone string, err error) {
+	location, exists := h.nodes[node]
+	if !exists {
+		return "", "", errors.New("Node '" + node + "' does not exist.")
+	}
+	return location[0], location[1], nil
+}
+
+// GetAllNodes returns a list of all nodes
+func (h *Hierarchy) GetAllNodes() []string {
+	var nodes []string
+	for node := range h.nodes {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Lookup finds the node for a key by placing it on a region, a zone in
+// that region and a node in that zone
+func (h *Hierarchy) Lookup(key string) (string, error) {
+	if len(h.nodes) == 0 {
+		return "", errors.New("No nodes exist")
+	}
+	node, _, err := h.root.descend(key, LevelNode)
+	return node, err
+}
+
+// Place returns the nodes for a key according to rule, in order of
+// precedence. The first node is the key's Lookup node. Replicas are spread
+// over as many domains above the Distinct level as possible. Fewer nodes
+// than requested are returned when the scope has fewer distinct domains.
+func (h *Hierarchy) Place(key string, rule PlacementRule) ([]string, error) {
+	distinct := rule.Distinct
+	if distinct == LevelAny {
+		distinct = LevelNode
+	}
+	if rule.Replicas < 1 || rule.Within < LevelAny || distinct > LevelNode || distinct <= rule.Within {
+		return nil, errors.New("Invalid placement rule")
+	}
+	if len(h.nodes) == 0 {
+		return nil, errors.New("No nodes exist")
+	}
+
+	_, scope, err := h.root.descend(key, rule.Within)
+	if err != nil {
+		return nil, err
+	}
+	domains := scope.domains(key, int(distinct-rule.Within), rule.Replicas)
+	nodes := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		node, _, err := domain.bucket.descend(key, LevelNode-distinct)
+		if err != nil {
+			return nil, err
+		}
+		if node == "" {
+			node = domain.name
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// namedBucket is a bucket with its name
+type namedBucket struct {
+	name   string
+	bucket *hierarchyBucket
+}
+
+// descend follows the key's Lookup path down depth levels, returning the
+// name and bucket it ends at. The name is empty when depth is 0.
+func (b *hierarchyBucket) descend(key string, depth Level) (string, *hierarchyBucket, error) {
+	name := ""
+	for ; depth > 0; depth-- {
+		var err error
+		if name, err = b.ring.Lookup(key); err != nil {
+			return "", nil, err
+		}
+		b = b.children[name]
+	}
+	return name, b, nil
+}
+
+// domains returns up to count buckets depth levels below b in order of
+// precedence for key. They are taken from b's children in turn, so they
+// spread over as many children as possible.
+func (b *hierarchyBucket) domains(key string, depth, count int) []namedBucket {
+	children, _ := b.ring.LookupList(key, min(count, len(b.children)))
+	if depth == 1 {
+		result := make([]namedBucket, len(children))
+		for i, child := range children {
+			result[i] = namedBucket{child, b.children[child]}
+		}
+		return result
+	}
+
+	lists := make([][]namedBucket, len(children))
+	for i, child := range children {
+		lists[i] = b.children[child].domains(key, depth-1, count)
+	}
+	var result []namedBucket
+	for round := 0; len(result) < count; round++ {
+		added := false
+		for _, list := range lists {
+			if round < len(list) && len(result) < count {
+				result = append(result, list[round])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,231 @@
+package flexihash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// testHierarchy has 2 regions of 3 zones of 2 nodes, named like "eu-b-2"
+func testHierarchy() *Hierarchy {
+	h := NewHierarchy()
+	for _, region := range []string{"eu", "us"} {
+		for _, zone := range []string{"a", "b", "c"} {
+			for i := 1; i <= 2; i++ {
+				h.AddNode(region, region+"-"+zone, region+"-"+zone+"-"+strconv.Itoa(i), 1)
+			}
+		}
+	}
+	return h
+}
+
+func TestHierarchyLookupFollowsLevels(t *testing.T) {
+	h := testHierarchy()
+	regions := h.root.ring
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		node, err := h.Lookup(key)
+		if err != nil {
+			t.Fatalf("Lookup failed: %v", err)
+		}
+		region, _ := regions.Lookup(key)
+		zone, _ := h.root.children[region].ring.Lookup(key)
+		expected, _ := h.root.children[region].children[zone].ring.Lookup(key)
+		if node != expected {
+			t.Fatalf("Key %s: expected %s, got %s", key, expected, node)
+		}
+	}
+}
+
+func TestHierarchyWeights(t *testing.T) {
+	h := NewHierarchy()
+	h.AddNode("eu", "eu-a", "n1", 1)
+	h.AddNode("eu", "eu-a", "n2", 2)
+	h.AddNode("us", "us-a", "n3", 0.5)
+
+	if weight := h.root.children["eu"].weight; weight != 3 {
+		t.Errorf("Expected eu weight 3, got %g", weight)
+	}
+	regions := h.root.ring.(*Rendezvous)
+	if weight := regions.targets[regions.targetIndex["eu"]].weight; weight != 3 {
+		t.Errorf("Expected eu weight 3 on the region ring, got %g", weight)
+	}
+
+	h.RemoveNode("n2")
+	if weight := h.root.children["eu"].weight; weight != 1 {
+		t.Errorf("Expected eu weight 1 after removal, got %g", weight)
+	}
+	h.RemoveNode("n3")
+	if _, exists := h.root.children["us"]; exists {
+		t.Error("Expected empty region to be removed")
+	}
+	if targets := h.root.ring.GetAllTargets(); len(targets) != 1 || targets[0] != "eu" {
+		t.Errorf("Expected only eu on the region ring, got %v", targets)
+	}
+}
+
+func TestHierarchyRegionShares(t *testing.T) {
+	// One replica per unit of weight keeps the zone rings small
+	h := NewHierarchyWithHasher(nil, 1)
+	for i := 0; i < 400; i++ {
+		region := "a"
+		if i >= 300 {
+			region = "b"
+		}
+		if err := h.AddNode(region, region+"-"+strconv.Itoa(i%4), "n"+strconv.Itoa(i), 100); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		node, _ := h.Lookup("key-" + strconv.Itoa(i))
+		region, _, _ := h.Location(node)
+		counts[region]++
+	}
+	if share := float64(counts["b"]) / 20000; math.Abs(share-0.25) > 0.02 {
+		t.Errorf("Expected region b to get ~25%% of keys, got %.1f%%", share*100)
+	}
+}
+
+func TestHierarchyRemoveNodeOnlyMovesItsKeys(t *testing.T) {
+	h := testHierarchy()
+	before := make(map[string]string)
+	for i := 0; i < 2000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		before[key], _ = h.Lookup(key)
+	}
+
+	h.RemoveNode("eu-b-1")
+	for key, node := range before {
+		after, _ := h.Lookup(key)
+		if after == "eu-b-1" {
+			t.Fatalf("Key %s: still on removed node", key)
+		}
+		// Only keys that were in the node's region can move
+		if region := node[:2]; after != node && region != "eu" {
+			t.Fatalf("Key %s: moved from %s to %s", key, node, after)
+		}
+	}
+}
+
+func TestHierarchyPlaceDistinctZonesInHomeRegion(t *testing.T) {
+	h := testHierarchy()
+	rule := PlacementRule{Replicas: 3, Within: LevelRegion, Distinct: LevelZone}
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		nodes, err := h.Place(key, rule)
+		if err != nil {
+			t.Fatalf("Place failed: %v", err)
+		}
+		primary, _ := h.Lookup(key)
+		if len(nodes) != 3 || nodes[0] != primary {
+			t.Fatalf("Key %s: expected 3 nodes starting with %s, got %v", key, primary, nodes)
+		}
+		homeRegion, _, _ := h.Location(primary)
+		zones := make(map[string]bool)
+		for _, node := range nodes {
+			region, zone, _ := h.Location(node)
+			if region != homeRegion {
+				t.Fatalf("Key %s: expected nodes in %s, got %v", key, homeRegion, nodes)
+			}
+			zones[zone] = true
+		}
+		if len(zones) != 3 {
+			t.Fatalf("Key %s: expected 3 zones, got %v", key, nodes)
+		}
+	}
+
+	// Only 3 zones per region
+	rule.Replicas = 4
+	if nodes, _ := h.Place("key", rule); len(nodes) != 3 {
+		t.Errorf("Expected 3 nodes when only 3 zones exist, got %v", nodes)
+	}
+}
+
+func TestHierarchyPlaceSpreadsOverRegions(t *testing.T) {
+	h := testHierarchy()
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		nodes, _ := h.Place(key, PlacementRule{Replicas: 4, Distinct: LevelZone})
+		if len(nodes) != 4 {
+			t.Fatalf("Key %s: expected 4 nodes, got %v", key, nodes)
+		}
+		regions := make(map[string]int)
+		zones := make(map[string]bool)
+		for _, node := range nodes {
+			region, zone, _ := h.Location(node)
+			regions[region]++
+			zones[zone] = true
+		}
+		if len(zones) != 4 || regions["eu"] != 2 || regions["us"] != 2 {
+			t.Fatalf("Key %s: expected 4 zones over both regions, got %v", key, nodes)
+		}
+
+		nodes, _ = h.Place(key, PlacementRule{Replicas: 2, Distinct: LevelRegion})
+		first, _, _ := h.Location(nodes[0])
+		second, _, _ := h.Location(nodes[1])
+		if len(nodes) != 2 || first == second {
+			t.Fatalf("Key %s: expected 2 regions, got %v", key, nodes)
+		}
+	}
+}
+
+func TestHierarchyPlaceNodesInHomeZone(t *testing.T) {
+	h := testHierarchy()
+	nodes, err := h.Place("key", PlacementRule{Replicas: 5, Within: LevelZone})
+	if err != nil {
+		t.Fatalf("Place failed: %v", err)
+	}
+	if len(nodes) != 2 || nodes[0][:4] != nodes[1][:4] {
+		t.Errorf("Expected both nodes of the home zone, got %v", nodes)
+	}
+}
+
+func TestHierarchyErrors(t *testing.T) {
+	h := NewHierarchy()
+	if _, err := h.Lookup("key"); err == nil {
+		t.Error("Expected error for empty hierarchy")
+	}
+	if _, err := h.Place("key", PlacementRule{Replicas: 1}); err == nil {
+		t.Error("Expected error for empty hierarchy")
+	}
+
+	h.AddNode("eu", "eu-a", "n1", 1)
+	if err := h.AddNode("us", "us-a", "n1", 1); err == nil {
+		t.Error("Expected error for duplicate node")
+	}
+	if err := h.RemoveNode("n2"); err == nil {
+		t.Error("Expected error for non-existent node")
+	}
+	for _, weight := range []float64{-1, math.NaN(), math.Inf(1), 1 << 20} {
+		if err := h.AddNode("us", "us-a", "n2", weight); err == nil {
+			t.Errorf("Expected error adding with weight %v", weight)
+		}
+	}
+	if _, exists := h.root.children["us"]; exists {
+		t.Error("Expected no region for a rejected node")
+	}
+	if nodes := h.GetAllNodes(); len(nodes) != 1 {
+		t.Errorf("Expected only n1 after rejected nodes, got %v", nodes)
+	}
+	if err := h.AddNode("eu", "eu-b", "n2", math.NaN()); err == nil {
+		t.Error("Expected error adding with weight NaN")
+	}
+	if _, exists := h.root.children["eu"].children["eu-b"]; exists {
+		t.Error("Expected no zone for a rejected node")
+	}
+	if err := h.AddNode("us", "us-a", "n2", 1); err != nil {
+		t.Errorf("Expected retry to succeed, got %v", err)
+	}
+	for _, rule := range []PlacementRule{
+		{Replicas: 0},
+		{Replicas: 2, Within: LevelZone, Distinct: LevelZone},
+		{Replicas: 2, Within: LevelNode},
+		{Replicas: 2, Distinct: Level(9)},
+	} {
+		if _, err := h.Place("key", rule); err == nil {
+			t.Errorf("Expected error for rule %+v", rule)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package flexihash
+
+import (
+	"errors"
+)
+
+// JumpHash implements Google's Jump Consistent Hash (Lamping and Veach).
+//
+// Targets are numbered buckets in the order they were added. Jump hash
+// needs no memory per virtual node and balances keys perfectly, but it
+// does not support weights, and only removing the most recently added
+// target gives minimal redistribution. Removing any other target moves
+// the last target into its bucket.
+type JumpHash struct {
+	hasher         Hasher
+	targets        []string
+	targetToBucket map[string]int
+}
+
+var _ Ring = (*JumpHash)(nil)
+
+// NewJumpHash creates a new JumpHash with the default CRC32 hasher
+func NewJumpHash() *JumpHash {
+	return NewJumpHashWithHasher(nil)
+}
+
+// NewJumpHashWithHasher creates a JumpHash with a custom hasher
+func NewJumpHashWithHasher(hasher Hasher) *JumpHash {
+	if hasher == nil {
+		hasher = &Crc32Hasher{}
+	}
+	return &JumpHash{
+		hasher:         hasher,
+		targetToBucket: make(map[string]int),
+	}
+}
+
+// AddTarget adds a target as the next bucket.
+// Jump hash does not support weights, so weight must be 0 or 1.
+func (jh *JumpHash) AddTarget(target string, weight float64) error {
+	if weight != 0 && weight != 1 {
+		return errors.New("Jump hash does not support weighted targets")
+	}
+	if _, exists := jh.targetToBucket[target]; exists {
+		return errors.New("Target '" + target + "' already exists.")
+	}
+	jh.targetToBucket[target] = len(jh.targets)
+	jh.targets = append(jh.targets, target)
+	return nil
+}
+
+// AddTargets adds multiple targets as consecutive buckets
+func (jh *JumpHash) AddTargets(targets []string, weight float64) error {
+	for _, target := range targets {
+		if err := jh.AddTarget(target, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveTarget removes a target, moving the last target into its bucket
+func (jh *JumpHash) RemoveTarget(target string) error {
+	bucket, exists := jh.targetToBucket[target]
+	if !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+
+	last := len(jh.targets) - 1
+	if bucket != last {
+		jh.targets[bucket] = jh.targets[last]
+		jh.targetToBucket[jh.targets[bucket]] = bucket
+	}
+	jh.targets = jh.targets[:last]
+	delete(jh.targetToBucket, target)
+	return nil
+}
+
+// GetAllTargets returns all targets in bucket order
+func (jh *JumpHash) GetAllTargets() []string {
+	targets := make([]string, len(jh.targets))
+	copy(targets, jh.targets)
+	return targets
+}
+
+// Lookup finds the target for a given resource
+func (jh *JumpHash) Lookup(resource string) (string, error) {
+	if len(jh.targets) == 0 {
+		return "", errors.New("No targets exist")
+	}
+	key := uint64(jh.hasher.Hash(resource))
+	return jh.targets[jumpHash(key, len(jh.targets))], nil
+}
+
+// LookupList returns a list of targets for the resource, in order of precedence.
+// After the first target, each one is chosen by rehashing the key over the
+// buckets that have not been picked yet.
+func (jh *JumpHash) LookupList(resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return nil, errors.New("Invalid count requested")
+	}
+	if requestedCount > len(jh.targets) {
+		requestedCount = len(jh.targets)
+	}
+
+	candidates := make([]string, len(jh.targets))
+	copy(candidates, jh.targets)
+	result := make([]string, 0, requestedCount)
+
+	key := uint64(jh.hasher.Hash(resource))
+	for len(result) < requestedCount {
+		i := jumpHash(key, len(candidates))
+		result = append(result, candidates[i])
+
+		last := len(candidates) - 1
+		candidates[i] = candidates[last]
+		candidates = candidates[:last]
+		key = mix64(key)
+	}
+	return result, nil
+}
+
+// jumpHash maps key to a bucket in [0, buckets)
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
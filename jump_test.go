@@ -0,0 +1,108 @@
+package flexihash
+
+import (
+	"strconv"
+	"testing"
+)
+
+// Reference values shared by other jump hash implementations
+func TestJumpHashVectors(t *testing.T) {
+	testCases := []struct {
+		key      uint64
+		buckets  int
+		expected int
+	}{
+		{1, 1, 0},
+		{42, 57, 43},
+		{0xDEAD10CC, 1, 0},
+		{0xDEAD10CC, 666, 361},
+		{256, 1024, 520},
+	}
+
+	for _, tc := range testCases {
+		if got := jumpHash(tc.key, tc.buckets); got != tc.expected {
+			t.Errorf("jumpHash(%d, %d): expected %d, got %d", tc.key, tc.buckets, tc.expected, got)
+		}
+	}
+}
+
+func TestJumpHashRing(t *testing.T) {
+	var ring Ring = NewJumpHash()
+	if _, err := ring.Lookup("resource"); err == nil {
+		t.Error("Expected error when looking up with no targets")
+	}
+	if err := ring.AddTarget("shard-0", 2); err == nil {
+		t.Error("Expected error for weighted target")
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := ring.AddTarget("shard-"+strconv.Itoa(i), 1); err != nil {
+			t.Fatalf("AddTarget failed: %v", err)
+		}
+	}
+	if err := ring.AddTarget("shard-0", 1); err == nil {
+		t.Error("Expected error when adding duplicate target")
+	}
+
+	targets, err := ring.LookupList("resource", 10)
+	if err != nil {
+		t.Fatalf("LookupList failed: %v", err)
+	}
+	if len(targets) != 4 {
+		t.Errorf("Expected 4 targets, got %v", targets)
+	}
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if seen[target] {
+			t.Errorf("Duplicate target in list: %s", target)
+		}
+		seen[target] = true
+	}
+
+	first, _ := ring.Lookup("resource")
+	if first != targets[0] {
+		t.Errorf("Lookup returned %s but LookupList starts with %s", first, targets[0])
+	}
+}
+
+func TestJumpHashMinimalRedistribution(t *testing.T) {
+	jh := NewJumpHash()
+	jh.AddTargets([]string{"s0", "s1", "s2", "s3"}, 1)
+
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		before[key], _ = jh.Lookup(key)
+	}
+
+	jh.AddTarget("s4", 1)
+	for key, target := range before {
+		after, _ := jh.Lookup(key)
+		if after != target && after != "s4" {
+			t.Errorf("Key %s moved from %s to %s instead of the new target", key, target, after)
+		}
+	}
+
+	jh.RemoveTarget("s4")
+	for key, target := range before {
+		if after, _ := jh.Lookup(key); after != target {
+			t.Errorf("Key %s did not return to %s after removing last target, got %s", key, target, after)
+		}
+	}
+}
+
+func TestJumpHashRemoveMiddleTarget(t *testing.T) {
+	jh := NewJumpHash()
+	jh.AddTargets([]string{"s0", "s1", "s2"}, 1)
+
+	if err := jh.RemoveTarget("s0"); err != nil {
+		t.Fatalf("RemoveTarget failed: %v", err)
+	}
+	targets := jh.GetAllTargets()
+	if len(targets) != 2 || targets[0] != "s2" || targets[1] != "s1" {
+		t.Errorf("Expected [s2 s1], got %v", targets)
+	}
+	if err := jh.RemoveTarget("s0"); err == nil {
+		t.Error("Expected error when removing non-existent target")
+	}
+}
@@ -0,0 +1,70 @@
+package flexihash
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// ketamaPointsPerServer is the number of continuum points of an average
+// weight target in libmemcached and twemproxy
+const ketamaPointsPerServer = 160
+
+// KetamaHasher uses the first 4 bytes of MD5 as a little-endian 32-bit
+// value (matches libmemcached and twemproxy ketama key hashing)
+type KetamaHasher struct{}
+
+// Hash returns an unsigned 32-bit hash from MD5
+func (h *KetamaHasher) Hash(str string) int {
+	digest := md5.Sum(stringBytes(str))
+	return int(binary.LittleEndian.Uint32(digest[:4]))
+}
+
+// NewKetamaFlexiHash creates a FlexiHash that places targets like
+// libmemcached's weighted ketama distribution and twemproxy's ketama
+// distribution with MD5 hashing, so Go clients select the same server as
+// C and PHP memcached clients sharing the pool.
+//
+// Each target gets 160 points scaled by its share of the total weight.
+// Points are taken four at a time from the MD5 digest of "<target>-<n>".
+// Name targets the way the other clients do: twemproxy uses the server's
+// name or "host:port", libmemcached uses "host" for port 11211 and
+// "host:port" otherwise. Since shares depend on every target's weight,
+// adding or removing a target recomputes all points, once per Build or
+// first lookup after the changes.
+func NewKetamaFlexiHash() *FlexiHash {
+	fh := NewFlexiHashWithHasher(&KetamaHasher{}, ketamaPointsPerServer)
+	fh.ketama = true
+	return fh
+}
+
+// placeKetamaPoints recomputes every target's continuum points.
+// It runs when the ring is built after targets changed.
+func (fh *FlexiHash) placeKetamaPoints() {
+	clear(fh.positionToTarget)
+	clear(fh.positionClaims)
+
+	totalWeight := 0.0
+	for _, weight := range fh.targetToWeight {
+		totalWeight += weight
+	}
+
+	for target, weight := range fh.targetToWeight {
+		// Single precision arithmetic and the epsilon match the C clients
+		pct := float32(weight) / float32(totalWeight)
+		share := float64(pct * float32(fh.replicas) / 4 * float32(fh.targetCount))
+		hashCount := int(math.Floor(share + 0.0000000001))
+
+		positions := make([]int, 0, hashCount*4)
+		for i := 0; i < hashCount; i++ {
+			digest := md5.Sum([]byte(target + "-" + strconv.Itoa(i)))
+			for x := 0; x < 4; x++ {
+				position := int(binary.LittleEndian.Uint32(digest[x*4:]))
+				claimPosition(fh.positionToTarget, fh.positionClaims, position, target)
+				positions = append(positions, position)
+			}
+		}
+		fh.targetToPositions[target] = positions
+	}
+}
@@ -0,0 +1,155 @@
+package flexihash
+
+import (
+	"strconv"
+	"testing"
+)
+
+// Golden vectors below were generated by an independent implementation of
+// twemproxy's ketama_update and ketama_dispatch with MD5 hashing, which
+// libmemcached's weighted ketama distribution also follows.
+
+func TestKetamaHasher(t *testing.T) {
+	// md5("foo") = acbd18db4cc2f85cedef654fccc4a4d8
+	if got := (&KetamaHasher{}).Hash("foo"); got != 0xdb18bdac {
+		t.Errorf("Expected %d, got %d", 0xdb18bdac, got)
+	}
+}
+
+func TestKetamaContinuum(t *testing.T) {
+	fh := NewKetamaFlexiHash()
+	fh.AddTarget("10.0.1.1:11211", 1)
+	fh.AddTarget("10.0.1.2:11211", 1)
+	fh.AddTarget("10.0.1.3:11211", 1)
+	fh.sortPositionTargets()
+
+	if fh.positionCount != 480 {
+		t.Errorf("Expected 480 points, got %d", fh.positionCount)
+	}
+	expected := []struct {
+		position int
+		target   string
+	}{
+		{4826654, "10.0.1.2:11211"},
+		{10171922, "10.0.1.1:11211"},
+	}
+	for i, exp := range expected {
+		position := fh.sortedPositions[i]
+		if position != exp.position || fh.positionToTarget[position] != exp.target {
+			t.Errorf("Point %d: expected %d -> %s, got %d -> %s",
+				i, exp.position, exp.target, position, fh.positionToTarget[position])
+		}
+	}
+}
+
+func TestKetamaGoldenVectors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		targets []string
+		weights []float64
+		lookups [][2]string
+	}{
+		{
+			name:    "equal weights",
+			targets: []string{"10.0.1.1:11211", "10.0.1.2:11211", "10.0.1.3:11211"},
+			weights: []float64{1, 1, 1},
+			lookups: [][2]string{
+				{"foo", "10.0.1.2:11211"},
+				{"bar", "10.0.1.1:11211"},
+				{"user:1", "10.0.1.1:11211"},
+				{"session:42", "10.0.1.1:11211"},
+				{"object-a", "10.0.1.1:11211"},
+				{"hello world", "10.0.1.2:11211"},
+				{"ketama", "10.0.1.3:11211"},
+				{"memcached", "10.0.1.3:11211"},
+			},
+		},
+		{
+			name:    "weighted",
+			targets: []string{"cache-a:11211", "cache-b:11211", "cache-c:11211"},
+			weights: []float64{1, 2, 3},
+			lookups: [][2]string{
+				{"foo", "cache-c:11211"},
+				{"bar", "cache-c:11211"},
+				{"user:1", "cache-a:11211"},
+				{"session:42", "cache-b:11211"},
+				{"object-a", "cache-c:11211"},
+				{"hello world", "cache-c:11211"},
+				{"ketama", "cache-c:11211"},
+				{"memcached", "cache-b:11211"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		fh := NewKetamaFlexiHash()
+		for i, target := range tc.targets {
+			fh.AddTarget(target, tc.weights[i])
+		}
+		for _, lookup := range tc.lookups {
+			if got, _ := fh.Lookup(lookup[0]); got != lookup[1] {
+				t.Errorf("%s: %s expected %s, got %s", tc.name, lookup[0], lookup[1], got)
+			}
+		}
+	}
+}
+
+func TestKetamaRemoveTargetRecomputesPoints(t *testing.T) {
+	fh := NewKetamaFlexiHash()
+	fh.AddTarget("cache-a:11211", 1)
+	fh.AddTarget("cache-b:11211", 2)
+	fh.AddTarget("cache-c:11211", 3)
+	fh.RemoveTarget("cache-c:11211")
+	fh.Build()
+
+	// Weights 1 and 2 get 1/3 and 2/3 of 2 × 160 points, rounded down to multiples of 4
+	if got := len(fh.targetToPositions["cache-a:11211"]); got != 104 {
+		t.Errorf("Expected 104 points for cache-a, got %d", got)
+	}
+	if got := len(fh.targetToPositions["cache-b:11211"]); got != 212 {
+		t.Errorf("Expected 212 points for cache-b, got %d", got)
+	}
+	if got := len(fh.positionToTarget); got != 316 {
+		t.Errorf("Expected 316 points on the continuum, got %d", got)
+	}
+}
+
+func TestKetamaPlacesPointsOncePerBuild(t *testing.T) {
+	targets := make([]string, 100)
+	for i := range targets {
+		targets[i] = "cache-" + strconv.Itoa(i) + ":11211"
+	}
+	fh := NewKetamaFlexiHash()
+	fh.AddTargets(targets, 1)
+	if len(fh.positionToTarget) != 0 {
+		t.Errorf("Expected points to wait for the build, got %d", len(fh.positionToTarget))
+	}
+
+	expected := NewKetamaFlexiHash()
+	for _, target := range targets {
+		expected.AddTarget(target, 1)
+		expected.Build()
+	}
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		want, _ := expected.Lookup(key)
+		if got, _ := fh.Lookup(key); got != want {
+			t.Fatalf("Key %s: expected %s, got %s", key, want, got)
+		}
+	}
+	if len(fh.positionToTarget) != len(expected.positionToTarget) {
+		t.Errorf("Expected %d points, got %d", len(expected.positionToTarget), len(fh.positionToTarget))
+	}
+}
+
+func BenchmarkKetamaAddTargets(b *testing.B) {
+	targets := make([]string, 500)
+	for i := range targets {
+		targets[i] = "cache-" + strconv.Itoa(i) + ":11211"
+	}
+	for i := 0; i < b.N; i++ {
+		fh := NewKetamaFlexiHash()
+		fh.AddTargets(targets, 1)
+		fh.Build()
+	}
+}
@@ -0,0 +1,87 @@
+package flexihash
+
+import (
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// Key is a lookup key that is not a string.
+//
+// Looking up a Key is equivalent to looking up the string it appends, so a
+// ring places a Key exactly where it places that string, and PHP
+// compatibility for string keys is unaffected.
+type Key interface {
+	// AppendKey appends the key's string form to dst and returns the result
+	AppendKey(dst []byte) []byte
+}
+
+// BytesKey is a key given as bytes, equivalent to string(key)
+type BytesKey []byte
+
+// AppendKey appends the bytes of the key
+func (k BytesKey) AppendKey(dst []byte) []byte {
+	return append(dst, k...)
+}
+
+// Uint64Key is an integer key, equivalent to its decimal form such as "42",
+// as PHP formats integers passed to Flexihash::lookup
+type Uint64Key uint64
+
+// AppendKey appends the decimal form of the key
+func (k Uint64Key) AppendKey(dst []byte) []byte {
+	return strconv.AppendUint(dst, uint64(k), 10)
+}
+
+var (
+	_ Key = BytesKey(nil)
+	_ Key = Uint64Key(0)
+)
+
+// keyBuffers holds buffers for formatting keys
+var keyBuffers = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// LookupBytes finds the target for a resource given as bytes.
+// It is equivalent to Lookup(string(resource)) without the conversion.
+func (fh *FlexiHash) LookupBytes(resource []byte) (string, error) {
+	return fh.Lookup(fh.keyString(resource))
+}
+
+// LookupUint64 finds the target for an integer resource.
+// It is equivalent to Lookup(strconv.FormatUint(resource, 10)).
+func (fh *FlexiHash) LookupUint64(resource uint64) (string, error) {
+	buf := keyBuffers.Get().(*[]byte)
+	defer keyBuffers.Put(buf)
+	*buf = strconv.AppendUint((*buf)[:0], resource, 10)
+	return fh.Lookup(fh.keyString(*buf))
+}
+
+// LookupKey finds the target for a Key
+func (fh *FlexiHash) LookupKey(resource Key) (string, error) {
+	buf := keyBuffers.Get().(*[]byte)
+	defer keyBuffers.Put(buf)
+	*buf = resource.AppendKey((*buf)[:0])
+	return fh.Lookup(fh.keyString(*buf))
+}
+
+// LookupListKey returns a list of targets for a Key, in order of precedence
+func (fh *FlexiHash) LookupListKey(resource Key, requestedCount int) ([]string, error) {
+	buf := keyBuffers.Get().(*[]byte)
+	defer keyBuffers.Put(buf)
+	*buf = resource.AppendKey((*buf)[:0])
+	return fh.LookupList(fh.keyString(*buf), requestedCount)
+}
+
+// keyString returns key as a string for a lookup, which never retains it.
+// The built-in hashers do not retain the strings they hash either, so for
+// them the string shares key's memory instead of copying it.
+func (fh *FlexiHash) keyString(key []byte) string {
+	switch fh.hasher.(type) {
+	case *Crc32Hasher, *Md5Hasher, *KetamaHasher, *XxHash64Hasher, *Murmur3Hasher,
+		*Murmur3x128Hasher, *Fnv1a32Hasher, *Fnv1a64Hasher, *SipHasher:
+		return unsafe.String(unsafe.SliceData(key), len(key))
+	}
+	return string(key)
+}
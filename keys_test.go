@@ -0,0 +1,91 @@
+package flexihash
+
+import (
+	"strconv"
+	"testing"
+)
+
+// customHasher is not a built-in hasher, so lookups copy keys for it
+type customHasher struct{}
+
+func (h customHasher) Hash(str string) int {
+	return (&Fnv1a32Hasher{}).Hash(str)
+}
+
+func TestKeyLookupsMatchStringLookups(t *testing.T) {
+	rings := map[string]*FlexiHash{
+		"crc32":       NewFlexiHash(),
+		"md5":         NewFlexiHashWithHasher(&Md5Hasher{}, 16),
+		"multi-probe": NewMultiProbeFlexiHash(nil, 0),
+		"custom":      NewFlexiHashWithHasher(customHasher{}, 16),
+	}
+	for name, fh := range rings {
+		fh.AddTargets([]string{"t1", "t2", "t3", "t4"}, 1)
+		for i := uint64(0); i < 500; i++ {
+			key := strconv.FormatUint(i*7919, 10)
+			expected, _ := fh.Lookup(key)
+			expectedList, _ := fh.LookupList(key, 3)
+
+			if got, _ := fh.LookupUint64(i * 7919); got != expected {
+				t.Fatalf("%s: LookupUint64(%s): expected %s, got %s", name, key, expected, got)
+			}
+			if got, _ := fh.LookupBytes([]byte(key)); got != expected {
+				t.Fatalf("%s: LookupBytes(%s): expected %s, got %s", name, key, expected, got)
+			}
+			if got, _ := fh.LookupKey(BytesKey(key)); got != expected {
+				t.Fatalf("%s: LookupKey(%s): expected %s, got %s", name, key, expected, got)
+			}
+			got, _ := fh.LookupListKey(Uint64Key(i*7919), 3)
+			if len(got) != 3 || got[0] != expectedList[0] || got[1] != expectedList[1] || got[2] != expectedList[2] {
+				t.Fatalf("%s: LookupListKey(%s): expected %v, got %v", name, key, expectedList, got)
+			}
+		}
+	}
+}
+
+func TestLookupBytesDoesNotRetainKey(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+
+	key := []byte("resource")
+	expected, _ := fh.Lookup("resource")
+	target, _ := fh.LookupBytes(key)
+	copy(key, "xxxxxxxx")
+	if target != expected {
+		t.Errorf("Expected %s, got %s", expected, target)
+	}
+}
+
+func TestConcurrentKeyLookups(t *testing.T) {
+	cfh := NewConcurrentFlexiHash()
+	cfh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	cfh.SetTargetStatus("t2", StatusDown)
+
+	for i := uint64(0); i < 200; i++ {
+		key := strconv.FormatUint(i, 10)
+		expected, _ := cfh.Lookup(key)
+		if got, _ := cfh.LookupUint64(i); got != expected {
+			t.Fatalf("LookupUint64(%d): expected %s, got %s", i, expected, got)
+		}
+		if got, _ := cfh.LookupBytes([]byte(key)); got != expected {
+			t.Fatalf("LookupBytes(%s): expected %s, got %s", key, expected, got)
+		}
+		if got, _ := cfh.LookupKey(Uint64Key(i)); got != expected {
+			t.Fatalf("LookupKey(%d): expected %s, got %s", i, expected, got)
+		}
+		if got, _ := cfh.LookupListKey(BytesKey(key), 3); len(got) != 2 || got[0] != expected {
+			t.Fatalf("LookupListKey(%s): expected 2 up targets from %s, got %v", key, expected, got)
+		}
+	}
+}
+
+func BenchmarkLookupUint64(b *testing.B) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2", "t3", "t4", "t5"}, 1)
+	fh.Lookup("warm up")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fh.LookupUint64(uint64(i))
+	}
+}
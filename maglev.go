@@ -0,0 +1,225 @@
+package flexihash
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// defaultMaglevTableSize is the lookup table size used when none is given.
+// It is prime, as Maglev requires.
+const defaultMaglevTableSize = 65537
+
+// Maglev implements Google's Maglev consistent hashing.
+//
+// Targets fill a fixed-size lookup table by walking their own permutation
+// of its slots, so lookups are a single O(1) table access and every target
+// owns an almost equal share of slots. Weighted targets claim slots
+// proportionally more often. The table is rebuilt lazily on the first
+// lookup after a change.
+type Maglev struct {
+	hasher    Hasher
+	tableSize int
+	weights   map[string]float64
+	names     []string
+	table     []int
+	built     bool
+}
+
+var _ Ring = (*Maglev)(nil)
+
+// NewMaglev creates a new Maglev with default settings
+func NewMaglev() *Maglev {
+	return NewMaglevWithHasher(nil, 0)
+}
+
+// NewMaglevWithHasher creates a Maglev with custom hasher and table size.
+// The table size is rounded up to the next prime; 0 uses 65537.
+// It should be much larger than the number of targets, e.g. 100 times.
+func NewMaglevWithHasher(hasher Hasher, tableSize int) *Maglev {
+	if hasher == nil {
+		hasher = &Crc32Hasher{}
+	}
+	if tableSize == 0 {
+		tableSize = defaultMaglevTableSize
+	}
+	return &Maglev{
+		hasher:    hasher,
+		tableSize: nextPrime(tableSize),
+		weights:   make(map[string]float64),
+	}
+}
+
+// TableSize returns the number of slots in the lookup table
+func (m *Maglev) TableSize() int {
+	return m.tableSize
+}
+
+// AddTarget adds a target with optional weight
+func (m *Maglev) AddTarget(target string, weight float64) error {
+	if weight == 0 {
+		weight = 1
+	}
+	if !(weight > 0) || math.IsInf(weight, 0) {
+		return errors.New("Invalid weight for target '" + target + "'")
+	}
+	if _, exists := m.weights[target]; exists {
+		return errors.New("Target '" + target + "' already exists.")
+	}
+	m.weights[target] = weight
+	m.built = false
+	return nil
+}
+
+// AddTargets adds multiple targets with optional weight
+func (m *Maglev) AddTargets(targets []string, weight float64) error {
+	for _, target := range targets {
+		if err := m.AddTarget(target, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveTarget removes a target
+func (m *Maglev) RemoveTarget(target string) error {
+	if _, exists := m.weights[target]; !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+	delete(m.weights, target)
+	m.built = false
+	return nil
+}
+
+// GetAllTargets returns a list of all potential targets
+func (m *Maglev) GetAllTargets() []string {
+	var targets []string
+	for target := range m.weights {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// Lookup finds the target for a given resource
+func (m *Maglev) Lookup(resource string) (string, error) {
+	if len(m.weights) == 0 {
+		return "", errors.New("No targets exist")
+	}
+	m.buildTable()
+	return m.names[m.table[m.slot(resource)]], nil
+}
+
+// LookupList returns a list of targets for the resource, in order of precedence.
+// After the first target, the table is walked forward from the resource's
+// slot collecting distinct targets.
+func (m *Maglev) LookupList(resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return nil, errors.New("Invalid count requested")
+	}
+	if len(m.weights) == 0 {
+		return []string{}, nil
+	}
+	m.buildTable()
+
+	var result []string
+	seen := make(map[int]bool)
+	slot := m.slot(resource)
+	for i := 0; i < m.tableSize && len(result) < requestedCount && len(result) < len(m.names); i++ {
+		index := m.table[slot]
+		if !seen[index] {
+			result = append(result, m.names[index])
+			seen[index] = true
+		}
+		slot++
+		if slot == m.tableSize {
+			slot = 0
+		}
+	}
+	return result, nil
+}
+
+// slot returns the table slot for a resource
+func (m *Maglev) slot(resource string) int {
+	return int(uint64(uint32(m.hasher.Hash(resource))) % uint64(m.tableSize))
+}
+
+// buildTable populates the lookup table if targets changed
+func (m *Maglev) buildTable() {
+	if m.built {
+		return
+	}
+
+	// Sort targets so the table does not depend on insertion order
+	m.names = m.names[:0]
+	for target := range m.weights {
+		m.names = append(m.names, target)
+	}
+	sort.Strings(m.names)
+
+	size := uint64(m.tableSize)
+	offsets := make([]uint64, len(m.names))
+	skips := make([]uint64, len(m.names))
+	maxWeight := 0.0
+	for i, name := range m.names {
+		h := uint64(uint32(m.hasher.Hash(name)))
+		offsets[i] = mix64(h) % size
+		skips[i] = mix64(h^0x9e3779b97f4a7c15)%(size-1) + 1
+		if m.weights[name] > maxWeight {
+			maxWeight = m.weights[name]
+		}
+	}
+
+	if m.table == nil {
+		m.table = make([]int, m.tableSize)
+	}
+	for i := range m.table {
+		m.table[i] = -1
+	}
+
+	// Each round, every target earns credit in proportion to its weight
+	// and claims its next preferred free slot for each whole credit
+	next := make([]uint64, len(m.names))
+	credits := make([]float64, len(m.names))
+	filled := 0
+	for filled < m.tableSize {
+		for i, name := range m.names {
+			credits[i] += m.weights[name] / maxWeight
+			if credits[i] < 1 {
+				continue
+			}
+			credits[i]--
+
+			slot := (offsets[i] + next[i]*skips[i]) % size
+			for m.table[slot] >= 0 {
+				next[i]++
+				slot = (offsets[i] + next[i]*skips[i]) % size
+			}
+			m.table[slot] = i
+			next[i]++
+			filled++
+			if filled == m.tableSize {
+				break
+			}
+		}
+	}
+	m.built = true
+}
+
+// nextPrime returns the smallest prime greater than or equal to n
+func nextPrime(n int) int {
+	if n <= 2 {
+		return 2
+	}
+	for ; ; n++ {
+		prime := true
+		for d := 2; d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package flexihash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestNextPrime(t *testing.T) {
+	testCases := map[int]int{1: 2, 2: 2, 4: 5, 13: 13, 100: 101, 65536: 65537}
+	for n, expected := range testCases {
+		if got := nextPrime(n); got != expected {
+			t.Errorf("nextPrime(%d): expected %d, got %d", n, expected, got)
+		}
+	}
+}
+
+func TestMaglevRing(t *testing.T) {
+	var ring Ring = NewMaglev()
+	if _, err := ring.Lookup("resource"); err == nil {
+		t.Error("Expected error when looking up with no targets")
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := ring.AddTarget("backend-"+strconv.Itoa(i), 1); err != nil {
+			t.Fatalf("AddTarget failed: %v", err)
+		}
+	}
+	if err := ring.AddTarget("backend-1", 1); err == nil {
+		t.Error("Expected error when adding duplicate target")
+	}
+	if _, err := ring.LookupList("resource", 0); err == nil {
+		t.Error("Expected error for invalid count")
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		first, _ := ring.Lookup(key)
+		targets, _ := ring.LookupList(key, 10)
+		if len(targets) != 5 || targets[0] != first {
+			t.Fatalf("Key %s: Lookup returned %s, LookupList returned %v", key, first, targets)
+		}
+	}
+}
+
+func TestMaglevTableBalance(t *testing.T) {
+	m := NewMaglevWithHasher(nil, 1000)
+	if m.TableSize() != 1009 {
+		t.Fatalf("Expected table size 1009, got %d", m.TableSize())
+	}
+	m.AddTargets([]string{"a", "b", "c", "d", "e"}, 1)
+	m.buildTable()
+
+	counts := make(map[int]int)
+	for _, index := range m.table {
+		counts[index]++
+	}
+	for index, count := range counts {
+		if count < 201 || count > 202 {
+			t.Errorf("Target %s owns %d slots, expected 201 or 202", m.names[index], count)
+		}
+	}
+}
+
+func TestMaglevWeightedTargets(t *testing.T) {
+	m := NewMaglev()
+	m.AddTarget("light", 1)
+	m.AddTarget("heavy", 3)
+	m.buildTable()
+
+	heavy := 0
+	for _, index := range m.table {
+		if m.names[index] == "heavy" {
+			heavy++
+		}
+	}
+	share := float64(heavy) / float64(m.TableSize())
+	if math.Abs(share-0.75) > 0.001 {
+		t.Errorf("Expected heavy target to own 75%% of slots, got %.2f%%", share*100)
+	}
+}
+
+func TestMaglevMinimalDisruption(t *testing.T) {
+	m := NewMaglev()
+	for i := 0; i < 10; i++ {
+		m.AddTarget("backend-"+strconv.Itoa(i), 1)
+	}
+	m.buildTable()
+	before := make([]string, m.TableSize())
+	for slot, index := range m.table {
+		before[slot] = m.names[index]
+	}
+
+	m.RemoveTarget("backend-3")
+	m.buildTable()
+	moved := 0
+	for slot, index := range m.table {
+		if before[slot] != "backend-3" && before[slot] != m.names[index] {
+			moved++
+		}
+	}
+	if fraction := float64(moved) / float64(m.TableSize()); fraction > 0.02 {
+		t.Errorf("Expected under 2%% of surviving slots to move, got %.2f%%", fraction*100)
+	}
+}
+
+func TestMaglevInvalidWeights(t *testing.T) {
+	m := NewMaglev()
+	for _, weight := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if err := m.AddTarget("t1", weight); err == nil {
+			t.Errorf("Expected error adding with weight %v", weight)
+		}
+	}
+	if _, err := m.Lookup("resource"); err == nil {
+		t.Error("Expected error when no targets exist")
+	}
+}
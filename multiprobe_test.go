@@ -0,0 +1,92 @@
+package flexihash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMultiProbeOnePositionPerTarget(t *testing.T) {
+	fh := NewMultiProbeFlexiHash(nil, 0)
+	if fh.probes != 21 || fh.replicas != 1 {
+		t.Fatalf("Expected 21 probes and 1 replica, got %d and %d", fh.probes, fh.replicas)
+	}
+	for i := 0; i < 10; i++ {
+		fh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+	fh.AddTarget("small", 0.5)
+
+	for target, positions := range fh.targetToPositions {
+		if len(positions) != 1 {
+			t.Errorf("Target %s: expected 1 position, got %d", target, len(positions))
+		}
+	}
+}
+
+func TestMultiProbeLookupListOrdering(t *testing.T) {
+	fh := NewMultiProbeFlexiHash(nil, 0)
+	for i := 0; i < 10; i++ {
+		fh.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		first, err := fh.Lookup(key)
+		if err != nil {
+			t.Fatalf("Lookup failed: %v", err)
+		}
+		targets, _ := fh.LookupList(key, 10)
+		if len(targets) != 10 || targets[0] != first {
+			t.Fatalf("Key %s: Lookup returned %s, LookupList returned %v", key, first, targets)
+		}
+	}
+}
+
+func TestMultiProbePicksClosestProbe(t *testing.T) {
+	mockHasher := &MockHasher{}
+	fh := NewMultiProbeFlexiHash(mockHasher, 2)
+
+	mockHasher.hashValue = 100
+	fh.AddTarget("t1", 1)
+	mockHasher.hashValue = 200
+	fh.AddTarget("t2", 1)
+
+	// Both probes hash to 150, so t2 is 50 away clockwise
+	mockHasher.hashValue = 150
+	targets, _ := fh.LookupList("resource", 2)
+	if len(targets) != 2 || targets[0] != "t2" || targets[1] != "t1" {
+		t.Errorf("Expected [t2 t1], got %v", targets)
+	}
+}
+
+func TestMultiProbeBalance(t *testing.T) {
+	peakToMean := func(fh *FlexiHash) float64 {
+		counts := make(map[string]int)
+		for i := 0; i < 50000; i++ {
+			target, _ := fh.Lookup("key-" + strconv.Itoa(i))
+			counts[target]++
+		}
+		peak := 0
+		for _, count := range counts {
+			if count > peak {
+				peak = count
+			}
+		}
+		return float64(peak) / (50000.0 / 50)
+	}
+
+	single := NewFlexiHashWithHasher(nil, 1)
+	multi := NewMultiProbeFlexiHash(nil, 0)
+	for i := 0; i < 50; i++ {
+		single.AddTarget("target"+strconv.Itoa(i), 1)
+		multi.AddTarget("target"+strconv.Itoa(i), 1)
+	}
+
+	singleRatio, multiRatio := peakToMean(single), peakToMean(multi)
+	t.Logf("Peak-to-mean: 1 position %.2f, multi-probe %.2f", singleRatio, multiRatio)
+	if multiRatio >= singleRatio {
+		t.Errorf("Expected multi-probe to balance better than one position per target")
+	}
+	if multiRatio > 1.5 {
+		t.Errorf("Expected multi-probe peak-to-mean under 1.5, got %.2f", multiRatio)
+	}
+}
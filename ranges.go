@@ -0,0 +1,68 @@
+package flexihash
+
+import (
+	"errors"
+)
+
+// OwnedRange is an arc of the hash space and the target that owns it
+type OwnedRange struct {
+	Arc    Arc
+	Target string
+}
+
+// Ranges returns the contiguous arcs of the hash space owned by each
+// target, in ring order. Together they cover the hash space exactly once.
+// The first arc usually wraps around the end of the hash space. Rings in
+// multi-probe mode are not supported, as ownership there does not follow arcs.
+func (fh *FlexiHash) Ranges() ([]OwnedRange, error) {
+	if fh.probes > 1 {
+		return nil, errors.New("Ranges are not supported for multi-probe rings")
+	}
+	fh.sortPositionTargets()
+
+	var ranges []OwnedRange
+	positions := fh.sortedPositions
+	for i, position := range positions {
+		previous := positions[len(positions)-1]
+		if i > 0 {
+			previous = positions[i-1]
+		}
+		target := fh.positionToTarget[position]
+
+		// Merge consecutive positions of the same target
+		if n := len(ranges); n > 0 && ranges[n-1].Target == target {
+			ranges[n-1].Arc.End = position + 1
+			continue
+		}
+		ranges = append(ranges, OwnedRange{
+			Arc:    Arc{Start: previous + 1, End: position + 1},
+			Target: target,
+		})
+	}
+
+	// The last arc continues into the first one across the wrap-around
+	if n := len(ranges); n > 1 && ranges[0].Target == ranges[n-1].Target {
+		ranges[0].Arc.Start = ranges[n-1].Arc.Start
+		ranges = ranges[:n-1]
+	}
+	return ranges, nil
+}
+
+// RangesFor returns the contiguous arcs of the hash space owned by a target, in ring order
+func (fh *FlexiHash) RangesFor(target string) ([]Arc, error) {
+	if _, exists := fh.targetToPositions[target]; !exists {
+		return nil, errors.New("Target '" + target + "' does not exist.")
+	}
+	ranges, err := fh.Ranges()
+	if err != nil {
+		return nil, err
+	}
+
+	arcs := []Arc{}
+	for _, r := range ranges {
+		if r.Target == target {
+			arcs = append(arcs, r.Arc)
+		}
+	}
+	return arcs, nil
+}
@@ -0,0 +1,100 @@
+package flexihash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRangesExact(t *testing.T) {
+	mockHasher := &MockHasher{}
+	fh := NewFlexiHashWithHasher(mockHasher, 1)
+
+	mockHasher.hashValue = 10
+	fh.AddTarget("a", 1)
+	mockHasher.hashValue = 20
+	fh.AddTarget("b", 1)
+	mockHasher.hashValue = 30
+	fh.AddTarget("c", 1)
+
+	ranges, err := fh.Ranges()
+	if err != nil {
+		t.Fatalf("Ranges failed: %v", err)
+	}
+	expected := []OwnedRange{
+		{Arc: Arc{Start: 31, End: 11}, Target: "a"},
+		{Arc: Arc{Start: 11, End: 21}, Target: "b"},
+		{Arc: Arc{Start: 21, End: 31}, Target: "c"},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("Range %d: expected %v, got %v", i, expected[i], ranges[i])
+		}
+	}
+
+	arcs, _ := fh.RangesFor("b")
+	if len(arcs) != 1 || arcs[0] != (Arc{Start: 11, End: 21}) {
+		t.Errorf("Expected [{11 21}], got %v", arcs)
+	}
+	if _, err := fh.RangesFor("missing"); err == nil {
+		t.Error("Expected error for non-existent target")
+	}
+}
+
+func TestRangesCoverHashSpaceAndMatchLookups(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"cache-1", "cache-2", "cache-3"}, 1)
+	fh.AddTarget("cache-4", 2)
+
+	ranges, _ := fh.Ranges()
+	var total uint64
+	for i, r := range ranges {
+		total += r.Arc.Size()
+		next := ranges[(i+1)%len(ranges)]
+		if uint32(r.Arc.End) != uint32(next.Arc.Start) {
+			t.Fatalf("Range %d ends at %d but next starts at %d", i, r.Arc.End, next.Arc.Start)
+		}
+		if r.Target == next.Target && len(ranges) > 1 {
+			t.Errorf("Adjacent ranges of %s were not merged", r.Target)
+		}
+	}
+	if total != hashSpaceSize {
+		t.Errorf("Ranges cover %d hash values, expected %d", total, uint64(hashSpaceSize))
+	}
+
+	hasher := &Crc32Hasher{}
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		target, _ := fh.Lookup(key)
+		arcs, _ := fh.RangesFor(target)
+		found := false
+		for _, arc := range arcs {
+			if arc.Contains(hasher.Hash(key)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Key %s maps to %s but is outside its ranges", key, target)
+		}
+	}
+}
+
+func TestRangesSingleTarget(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTarget("only", 1)
+
+	ranges, _ := fh.Ranges()
+	if len(ranges) != 1 || ranges[0].Target != "only" || ranges[0].Arc.Fraction() != 1 {
+		t.Errorf("Expected one range covering the hash space, got %v", ranges)
+	}
+
+	if ranges, _ := NewFlexiHash().Ranges(); len(ranges) != 0 {
+		t.Errorf("Expected no ranges for an empty ring, got %v", ranges)
+	}
+	if _, err := NewMultiProbeFlexiHash(nil, 0).Ranges(); err == nil {
+		t.Error("Expected error for multi-probe ring")
+	}
+}
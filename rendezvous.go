@@ -0,0 +1,163 @@
+package flexihash
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Rendezvous implements rendezvous (highest random weight) hashing.
+//
+// Each lookup scores every target against the resource and picks the
+// highest score, so there are no virtual nodes to tune. Weights use the
+// logarithmic method, score = weight / -ln(h), which assigns each target
+// a share of keys proportional to its weight. Lookups are O(T) in the
+// number of targets, which suits small clusters.
+type Rendezvous struct {
+	hasher      Hasher
+	targets     []rendezvousTarget
+	targetIndex map[string]int
+}
+
+type rendezvousTarget struct {
+	name   string
+	hash   uint32
+	weight float64
+}
+
+type rendezvousScore struct {
+	target string
+	score  float64
+}
+
+var _ Ring = (*Rendezvous)(nil)
+
+// NewRendezvous creates a new Rendezvous with the default CRC32 hasher
+func NewRendezvous() *Rendezvous {
+	return NewRendezvousWithHasher(nil)
+}
+
+// NewRendezvousWithHasher creates a Rendezvous with a custom hasher
+func NewRendezvousWithHasher(hasher Hasher) *Rendezvous {
+	if hasher == nil {
+		hasher = &Crc32Hasher{}
+	}
+	return &Rendezvous{
+		hasher:      hasher,
+		targetIndex: make(map[string]int),
+	}
+}
+
+// AddTarget adds a target with optional weight
+func (r *Rendezvous) AddTarget(target string, weight float64) error {
+	if weight == 0 {
+		weight = 1
+	}
+	if !(weight > 0) || math.IsInf(weight, 0) {
+		return errors.New("Invalid weight for target '" + target + "'")
+	}
+	if _, exists := r.targetIndex[target]; exists {
+		return errors.New("Target '" + target + "' already exists.")
+	}
+	r.targetIndex[target] = len(r.targets)
+	r.targets = append(r.targets, rendezvousTarget{
+		name:   target,
+		hash:   uint32(r.hasher.Hash(target)),
+		weight: weight,
+	})
+	return nil
+}
+
+// AddTargets adds multiple targets with optional weight
+func (r *Rendezvous) AddTargets(targets []string, weight float64) error {
+	for _, target := range targets {
+		if err := r.AddTarget(target, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveTarget removes a target
+func (r *Rendezvous) RemoveTarget(target string) error {
+	i, exists := r.targetIndex[target]
+	if !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+
+	last := len(r.targets) - 1
+	if i != last {
+		r.targets[i] = r.targets[last]
+		r.targetIndex[r.targets[i].name] = i
+	}
+	r.targets = r.targets[:last]
+	delete(r.targetIndex, target)
+	return nil
+}
+
+// GetAllTargets returns a list of all potential targets
+func (r *Rendezvous) GetAllTargets() []string {
+	targets := make([]string, 0, len(r.targets))
+	for _, t := range r.targets {
+		targets = append(targets, t.name)
+	}
+	return targets
+}
+
+// Lookup finds the target with the highest score for a given resource
+func (r *Rendezvous) Lookup(resource string) (string, error) {
+	if len(r.targets) == 0 {
+		return "", errors.New("No targets exist")
+	}
+
+	keyHash := uint32(r.hasher.Hash(resource))
+	best := rendezvousScore{target: r.targets[0].name, score: r.targets[0].score(keyHash)}
+	for _, t := range r.targets[1:] {
+		candidate := rendezvousScore{target: t.name, score: t.score(keyHash)}
+		if candidate.beats(best) {
+			best = candidate
+		}
+	}
+	return best.target, nil
+}
+
+// LookupList returns a list of targets for the resource, in order of descending score
+func (r *Rendezvous) LookupList(resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return nil, errors.New("Invalid count requested")
+	}
+
+	keyHash := uint32(r.hasher.Hash(resource))
+	scores := make([]rendezvousScore, len(r.targets))
+	for i, t := range r.targets {
+		scores[i] = rendezvousScore{target: t.name, score: t.score(keyHash)}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].beats(scores[j])
+	})
+
+	if requestedCount > len(scores) {
+		requestedCount = len(scores)
+	}
+	result := make([]string, requestedCount)
+	for i := range result {
+		result[i] = scores[i].target
+	}
+	return result, nil
+}
+
+// score returns the weighted score of the target for a resource hash
+func (t rendezvousTarget) score(keyHash uint32) float64 {
+	x := mix64(uint64(t.hash)<<32 | uint64(keyHash))
+	// Map to a uniform value in the open interval (0, 1)
+	u := (float64(x>>11) + 0.5) / (1 << 53)
+	return t.weight / -math.Log(u)
+}
+
+// beats reports whether s ranks before other, breaking ties by target name
+func (s rendezvousScore) beats(other rendezvousScore) bool {
+	if s.score != other.score {
+		return s.score > other.score
+	}
+	return s.target > other.target
+}
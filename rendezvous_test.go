@@ -0,0 +1,89 @@
+package flexihash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestRendezvousRing(t *testing.T) {
+	var ring Ring = NewRendezvous()
+	if _, err := ring.Lookup("resource"); err == nil {
+		t.Error("Expected error when looking up with no targets")
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := ring.AddTarget("node-"+strconv.Itoa(i), 1); err != nil {
+			t.Fatalf("AddTarget failed: %v", err)
+		}
+	}
+	if err := ring.AddTarget("node-1", 1); err == nil {
+		t.Error("Expected error when adding duplicate target")
+	}
+	if _, err := ring.LookupList("resource", 0); err == nil {
+		t.Error("Expected error for invalid count")
+	}
+
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		first, _ := ring.Lookup(key)
+		targets, _ := ring.LookupList(key, 3)
+		if len(targets) != 3 || targets[0] != first {
+			t.Fatalf("Key %s: Lookup returned %s, LookupList returned %v", key, first, targets)
+		}
+		if targets[0] == targets[1] || targets[1] == targets[2] || targets[0] == targets[2] {
+			t.Fatalf("Duplicate target in list: %v", targets)
+		}
+	}
+}
+
+func TestRendezvousMinimalRedistribution(t *testing.T) {
+	r := NewRendezvous()
+	r.AddTargets([]string{"a", "b", "c", "d"}, 1)
+
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		before[key], _ = r.Lookup(key)
+	}
+
+	r.RemoveTarget("b")
+	for key, target := range before {
+		after, _ := r.Lookup(key)
+		if target != "b" && after != target {
+			t.Errorf("Key %s moved from %s to %s although its target was not removed", key, target, after)
+		}
+	}
+}
+
+func TestRendezvousWeightedTargets(t *testing.T) {
+	r := NewRendezvous()
+	r.AddTarget("light", 1)
+	r.AddTarget("heavy", 3)
+
+	counts := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		target, _ := r.Lookup("resource-" + strconv.Itoa(i))
+		counts[target]++
+	}
+
+	share := float64(counts["heavy"]) / 20000
+	if math.Abs(share-0.75) > 0.03 {
+		t.Errorf("Expected heavy target to get ~75%% of keys, got %.1f%%", share*100)
+	}
+}
+
+func TestRendezvousInvalidWeights(t *testing.T) {
+	r := NewRendezvous()
+	r.AddTarget("good", 1)
+	for _, weight := range []float64{-1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if err := r.AddTarget("bad", weight); err == nil {
+			t.Errorf("Expected error adding with weight %v", weight)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if target, _ := r.Lookup("resource-" + strconv.Itoa(i)); target != "good" {
+			t.Fatalf("Expected only the valid target, got %s", target)
+		}
+	}
+}
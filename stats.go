@@ -0,0 +1,76 @@
+package flexihash
+
+import (
+	"math"
+	"sort"
+)
+
+// TargetStats describes a target's exact share of the hash space
+type TargetStats struct {
+	Target        string
+	Weight        float64
+	Positions     int
+	Share         float64 // fraction of the hash space owned
+	ExpectedShare float64 // weight divided by the total weight
+	Ratio         float64 // Share divided by ExpectedShare, 1 is perfectly balanced
+}
+
+// RingStats describes how evenly a ring spreads the hash space over its targets
+type RingStats struct {
+	Targets    []TargetStats // sorted by target name
+	StdDev     float64       // standard deviation of the targets' Ratio
+	PeakToMean float64       // highest Ratio divided by the mean Ratio
+}
+
+// Stats computes each target's exact share of the hash space from the
+// ring's arcs, rather than by sampling lookups. Keys are assumed to hash
+// uniformly. Rings in multi-probe mode are not supported.
+func (fh *FlexiHash) Stats() (*RingStats, error) {
+	ranges, err := fh.Ranges()
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]uint64)
+	for _, r := range ranges {
+		sizes[r.Target] += r.Arc.Size()
+	}
+	totalWeight := 0.0
+	for _, weight := range fh.targetToWeight {
+		totalWeight += weight
+	}
+
+	stats := &RingStats{Targets: make([]TargetStats, 0, len(fh.targetToWeight))}
+	for target, weight := range fh.targetToWeight {
+		ts := TargetStats{
+			Target:        target,
+			Weight:        weight,
+			Positions:     len(fh.targetToPositions[target]),
+			Share:         float64(sizes[target]) / hashSpaceSize,
+			ExpectedShare: weight / totalWeight,
+		}
+		ts.Ratio = ts.Share / ts.ExpectedShare
+		stats.Targets = append(stats.Targets, ts)
+	}
+	sort.Slice(stats.Targets, func(i, j int) bool {
+		return stats.Targets[i].Target < stats.Targets[j].Target
+	})
+	if len(stats.Targets) == 0 {
+		return stats, nil
+	}
+
+	mean, peak := 0.0, 0.0
+	for _, ts := range stats.Targets {
+		mean += ts.Ratio
+		peak = math.Max(peak, ts.Ratio)
+	}
+	mean /= float64(len(stats.Targets))
+
+	variance := 0.0
+	for _, ts := range stats.Targets {
+		variance += (ts.Ratio - mean) * (ts.Ratio - mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(stats.Targets)))
+	stats.PeakToMean = peak / mean
+	return stats, nil
+}
@@ -0,0 +1,82 @@
+package flexihash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStatsExact(t *testing.T) {
+	mockHasher := &MockHasher{}
+	fh := NewFlexiHashWithHasher(mockHasher, 1)
+
+	mockHasher.hashValue = 0
+	fh.AddTarget("a", 1)
+	mockHasher.hashValue = 1 << 30
+	fh.AddTarget("b", 1)
+
+	stats, err := fh.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if len(stats.Targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %v", stats.Targets)
+	}
+
+	// b owns (0, 2^30], a owns the remaining three quarters
+	a, b := stats.Targets[0], stats.Targets[1]
+	if a.Target != "a" || a.Share != 0.75 || a.ExpectedShare != 0.5 || a.Ratio != 1.5 {
+		t.Errorf("Unexpected stats for a: %+v", a)
+	}
+	if b.Target != "b" || b.Share != 0.25 || b.Ratio != 0.5 {
+		t.Errorf("Unexpected stats for b: %+v", b)
+	}
+	if stats.StdDev != 0.5 {
+		t.Errorf("Expected standard deviation 0.5, got %g", stats.StdDev)
+	}
+	if stats.PeakToMean != 1.5 {
+		t.Errorf("Expected peak-to-mean 1.5, got %g", stats.PeakToMean)
+	}
+}
+
+func TestStatsWeightedTargets(t *testing.T) {
+	fh := NewFlexiHashWithHasher(nil, 256)
+	fh.AddTarget("light", 1)
+	fh.AddTarget("heavy", 2)
+
+	stats, _ := fh.Stats()
+	total := 0.0
+	for _, ts := range stats.Targets {
+		total += ts.Share
+		if math.Abs(ts.Ratio-1) > 0.2 {
+			t.Errorf("Target %s is badly balanced: %+v", ts.Target, ts)
+		}
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("Shares sum to %g, expected 1", total)
+	}
+	if stats.Targets[0].Target != "heavy" || stats.Targets[0].ExpectedShare != 2.0/3 {
+		t.Errorf("Unexpected stats for heavy: %+v", stats.Targets[0])
+	}
+}
+
+func TestStatsMoreReplicasBalanceBetter(t *testing.T) {
+	stdDev := func(replicas int) float64 {
+		fh := NewFlexiHashWithHasher(nil, replicas)
+		fh.AddTargets([]string{"s1", "s2", "s3", "s4", "s5"}, 1)
+		stats, _ := fh.Stats()
+		return stats.StdDev
+	}
+	if low, high := stdDev(4), stdDev(512); high >= low {
+		t.Errorf("Expected 512 replicas (%g) to balance better than 4 (%g)", high, low)
+	}
+}
+
+func TestStatsEmptyRing(t *testing.T) {
+	stats, err := NewFlexiHash().Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if len(stats.Targets) != 0 || stats.PeakToMean != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
@@ -0,0 +1,87 @@
+package flexihash
+
+import "errors"
+
+// TargetStatus is the health state of a target
+type TargetStatus int
+
+const (
+	// StatusUp targets receive lookups
+	StatusUp TargetStatus = iota
+	// StatusDown targets are skipped by lookups until they are up again
+	StatusDown
+	// StatusDraining targets are skipped by lookups while they hand off
+	// their work, usually before being removed
+	StatusDraining
+)
+
+// String returns the status name
+func (s TargetStatus) String() string {
+	switch s {
+	case StatusUp:
+		return "up"
+	case StatusDown:
+		return "down"
+	case StatusDraining:
+		return "draining"
+	}
+	return "unknown"
+}
+
+// ParseTargetStatus parses a status name as returned by String
+func ParseTargetStatus(name string) (TargetStatus, error) {
+	for _, status := range []TargetStatus{StatusUp, StatusDown, StatusDraining} {
+		if status.String() == name {
+			return status, nil
+		}
+	}
+	return 0, errors.New("Invalid status '" + name + "'")
+}
+
+func (s TargetStatus) valid() bool {
+	return s >= StatusUp && s <= StatusDraining
+}
+
+// SetTargetStatus sets the status of a target in O(1).
+// Lookups skip targets that are not up by continuing clockwise, but their
+// positions stay on the ring, so their keys return to them once they are up.
+func (fh *FlexiHash) SetTargetStatus(target string, status TargetStatus) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
+	if _, exists := fh.targetToPositions[target]; !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+	if !status.valid() {
+		return errors.New("Invalid status")
+	}
+	if status == StatusUp {
+		delete(fh.targetStatus, target)
+	} else {
+		fh.targetStatus[target] = status
+	}
+	return nil
+}
+
+// Status returns the status of a target
+func (fh *FlexiHash) Status(target string) (TargetStatus, error) {
+	if _, exists := fh.targetToPositions[target]; !exists {
+		return 0, errors.New("Target '" + target + "' does not exist.")
+	}
+	if fh.sharedStatus != nil {
+		if status, found := fh.sharedStatus.Load(target); found {
+			return status.(TargetStatus), nil
+		}
+		return StatusUp, nil
+	}
+	return fh.targetStatus[target], nil
+}
+
+// isUp reports whether lookups may return target
+func (fh *FlexiHash) isUp(target string) bool {
+	if fh.sharedStatus != nil {
+		_, notUp := fh.sharedStatus.Load(target)
+		return !notUp
+	}
+	return fh.targetStatus[target] == StatusUp
+}
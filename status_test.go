@@ -0,0 +1,148 @@
+package flexihash
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestTargetStatusSkipsTargetsThatAreNotUp(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2", "t3", "t4"}, 1)
+
+	before := make(map[string][]string)
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		before[key], _ = fh.LookupList(key, 4)
+	}
+
+	if err := fh.SetTargetStatus("t2", StatusDown); err != nil {
+		t.Fatalf("SetTargetStatus failed: %v", err)
+	}
+	fh.SetTargetStatus("t3", StatusDraining)
+	for key, order := range before {
+		var expected []string
+		for _, target := range order {
+			if target == "t1" || target == "t4" {
+				expected = append(expected, target)
+			}
+		}
+		got, _ := fh.LookupList(key, 4)
+		if len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+			t.Fatalf("Key %s: expected %v, got %v", key, expected, got)
+		}
+		if target, _ := fh.Lookup(key); target != expected[0] {
+			t.Fatalf("Key %s: expected %s, got %s", key, expected[0], target)
+		}
+	}
+
+	// Keys return to their targets once they are up again
+	fh.SetTargetStatus("t2", StatusUp)
+	fh.SetTargetStatus("t3", StatusUp)
+	for key, order := range before {
+		if target, _ := fh.Lookup(key); target != order[0] {
+			t.Fatalf("Key %s: expected %s after recovery, got %s", key, order[0], target)
+		}
+	}
+}
+
+func TestTargetStatusErrors(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTarget("t1", 1)
+
+	if err := fh.SetTargetStatus("t2", StatusDown); err == nil {
+		t.Error("Expected error for non-existent target")
+	}
+	if err := fh.SetTargetStatus("t1", TargetStatus(7)); err == nil {
+		t.Error("Expected error for invalid status")
+	}
+
+	fh.SetTargetStatus("t1", StatusDown)
+	if status, _ := fh.Status("t1"); status != StatusDown {
+		t.Errorf("Expected down, got %s", status)
+	}
+	if _, err := fh.Lookup("key"); err == nil || err.Error() != "No targets are up" {
+		t.Errorf("Expected 'No targets are up', got %v", err)
+	}
+
+	// Removing a target forgets its status
+	fh.RemoveTarget("t1")
+	fh.AddTarget("t1", 1)
+	if status, _ := fh.Status("t1"); status != StatusUp {
+		t.Errorf("Expected up after re-adding, got %s", status)
+	}
+}
+
+func TestParseTargetStatus(t *testing.T) {
+	for _, status := range []TargetStatus{StatusUp, StatusDown, StatusDraining} {
+		parsed, err := ParseTargetStatus(status.String())
+		if err != nil || parsed != status {
+			t.Errorf("Expected %s, got %s (%v)", status, parsed, err)
+		}
+	}
+	if _, err := ParseTargetStatus("sideways"); err == nil {
+		t.Error("Expected error for unknown status")
+	}
+}
+
+func TestConcurrentTargetStatus(t *testing.T) {
+	cfh := NewConcurrentFlexiHash()
+	cfh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	snapshot := cfh.Snapshot()
+
+	if err := cfh.SetTargetStatus("t1", StatusDown); err != nil {
+		t.Fatalf("SetTargetStatus failed: %v", err)
+	}
+	if cfh.Snapshot() != snapshot {
+		t.Error("Expected status change not to publish a new snapshot")
+	}
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		if target, _ := cfh.Lookup(key); target == "t1" {
+			t.Fatalf("Key %s: expected down target to be skipped", key)
+		}
+		targets, _ := cfh.LookupList(key, 3)
+		if len(targets) != 2 {
+			t.Fatalf("Key %s: expected 2 up targets, got %v", key, targets)
+		}
+	}
+	if status, _ := cfh.Status("t1"); status != StatusDown {
+		t.Errorf("Expected down, got %s", status)
+	}
+	if target, _ := snapshot.Lookup("key-0"); target == "t1" {
+		t.Error("Expected snapshot lookups to skip the down target")
+	}
+
+	cfh.RemoveTarget("t1")
+	cfh.AddTarget("t1", 1)
+	if status, _ := cfh.Status("t1"); status != StatusUp {
+		t.Errorf("Expected up after re-adding, got %s", status)
+	}
+	if err := cfh.SetTargetStatus("t4", StatusDown); err == nil {
+		t.Error("Expected error for non-existent target")
+	}
+}
+
+// Run with -race to check status changes alongside lookups
+func TestConcurrentTargetStatusParallel(t *testing.T) {
+	cfh := NewConcurrentFlexiHash()
+	cfh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if _, err := cfh.Lookup("key-" + strconv.Itoa(g) + "-" + strconv.Itoa(i)); err != nil {
+					t.Errorf("Lookup failed: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	for i := 0; i < 1000; i++ {
+		cfh.SetTargetStatus("t"+strconv.Itoa(i%2+1), TargetStatus(i%3))
+	}
+	wg.Wait()
+}
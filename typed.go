@@ -0,0 +1,142 @@
+package flexihash
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// TypedFlexiHash is a ring whose targets carry a payload of type T, such as
+// an address or a client connection, so a lookup returns the payload
+// directly. Targets are placed by their ID exactly as FlexiHash places
+// target names.
+//
+// Like ConcurrentFlexiHash it is safe for concurrent use: writers publish
+// immutable snapshots of the ring and payloads, and lookups never take a
+// lock.
+type TypedFlexiHash[T any] struct {
+	mu    sync.Mutex // serializes writers
+	state atomic.Pointer[typedState[T]]
+}
+
+// typedState is an immutable snapshot of a TypedFlexiHash
+type typedState[T any] struct {
+	ring     *FlexiHash
+	payloads map[string]T
+}
+
+// NewTypedFlexiHash creates a new TypedFlexiHash with default settings
+func NewTypedFlexiHash[T any]() *TypedFlexiHash[T] {
+	return NewTypedFlexiHashWithHasher[T](nil, 0)
+}
+
+// NewTypedFlexiHashWithHasher creates a TypedFlexiHash with custom hasher and replicas
+func NewTypedFlexiHashWithHasher[T any](hasher Hasher, replicas int) *TypedFlexiHash[T] {
+	r := &TypedFlexiHash[T]{}
+	r.state.Store(&typedState[T]{
+		ring:     NewFlexiHashWithHasher(hasher, replicas),
+		payloads: make(map[string]T),
+	})
+	return r
+}
+
+// update applies fn to a copy of the current state and publishes it.
+// Nothing is published if fn returns an error.
+func (r *TypedFlexiHash[T]) update(fn func(*typedState[T]) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.state.Load()
+	next := &typedState[T]{
+		ring:     current.ring.clone(),
+		payloads: make(map[string]T, len(current.payloads)+1),
+	}
+	for id, payload := range current.payloads {
+		next.payloads[id] = payload
+	}
+	if err := fn(next); err != nil {
+		return err
+	}
+	next.ring.Freeze()
+	r.state.Store(next)
+	return nil
+}
+
+// AddTarget adds a target with its payload and optional weight
+func (r *TypedFlexiHash[T]) AddTarget(id string, payload T, weight float64) error {
+	return r.update(func(s *typedState[T]) error {
+		if err := s.ring.AddTarget(id, weight); err != nil {
+			return err
+		}
+		s.payloads[id] = payload
+		return nil
+	})
+}
+
+// SetPayload replaces the payload of an existing target without moving any keys
+func (r *TypedFlexiHash[T]) SetPayload(id string, payload T) error {
+	return r.update(func(s *typedState[T]) error {
+		if _, exists := s.payloads[id]; !exists {
+			return errors.New("Target '" + id + "' does not exist.")
+		}
+		s.payloads[id] = payload
+		return nil
+	})
+}
+
+// RemoveTarget removes a target and its payload
+func (r *TypedFlexiHash[T]) RemoveTarget(id string) error {
+	return r.update(func(s *typedState[T]) error {
+		if err := s.ring.RemoveTarget(id); err != nil {
+			return err
+		}
+		delete(s.payloads, id)
+		return nil
+	})
+}
+
+// Target returns the payload of a target
+func (r *TypedFlexiHash[T]) Target(id string) (T, error) {
+	payload, exists := r.state.Load().payloads[id]
+	if !exists {
+		return payload, errors.New("Target '" + id + "' does not exist.")
+	}
+	return payload, nil
+}
+
+// GetAllTargets returns the IDs of all targets
+func (r *TypedFlexiHash[T]) GetAllTargets() []string {
+	return r.state.Load().ring.GetAllTargets()
+}
+
+// Snapshot returns the current immutable ring of target IDs, for use with
+// the analysis methods of FlexiHash. It is frozen, so changing it returns
+// an error.
+func (r *TypedFlexiHash[T]) Snapshot() *FlexiHash {
+	return r.state.Load().ring
+}
+
+// Lookup returns the payload of the target for a given resource
+func (r *TypedFlexiHash[T]) Lookup(resource string) (T, error) {
+	s := r.state.Load()
+	id, err := s.ring.Lookup(resource)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return s.payloads[id], nil
+}
+
+// LookupList returns the payloads of the targets for the resource, in order of precedence
+func (r *TypedFlexiHash[T]) LookupList(resource string, requestedCount int) ([]T, error) {
+	s := r.state.Load()
+	ids, err := s.ring.LookupList(resource, requestedCount)
+	if err != nil {
+		return nil, err
+	}
+	payloads := make([]T, len(ids))
+	for i, id := range ids {
+		payloads[i] = s.payloads[id]
+	}
+	return payloads, nil
+}
@@ -0,0 +1,104 @@
+package flexihash
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type testBackend struct {
+	name string
+	addr string
+}
+
+func TestTypedFlexiHashMatchesFlexiHash(t *testing.T) {
+	fh := NewFlexiHash()
+	typed := NewTypedFlexiHash[*testBackend]()
+	for i, name := range []string{"cache-1", "cache-2", "cache-3"} {
+		fh.AddTarget(name, float64(i+1))
+		if err := typed.AddTarget(name, &testBackend{name, "10.0.0." + strconv.Itoa(i+1) + ":11211"}, float64(i+1)); err != nil {
+			t.Fatalf("AddTarget failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		expected, _ := fh.LookupList(key, 2)
+		backend, err := typed.Lookup(key)
+		if err != nil || backend.name != expected[0] {
+			t.Fatalf("Key %s: expected %s, got %+v (%v)", key, expected[0], backend, err)
+		}
+		backends, _ := typed.LookupList(key, 2)
+		if len(backends) != 2 || backends[0].name != expected[0] || backends[1].name != expected[1] {
+			t.Fatalf("Key %s: expected %v, got %+v", key, expected, backends)
+		}
+	}
+
+	fingerprint, _ := fh.Fingerprint()
+	if typedFingerprint, _ := typed.Snapshot().Fingerprint(); typedFingerprint != fingerprint {
+		t.Errorf("Expected fingerprint %s, got %s", fingerprint, typedFingerprint)
+	}
+}
+
+func TestTypedFlexiHashPayloads(t *testing.T) {
+	typed := NewTypedFlexiHashWithHasher[string](&Md5Hasher{}, 16)
+	typed.AddTarget("a", "10.0.0.1", 1)
+	typed.AddTarget("b", "10.0.0.2", 1)
+
+	if err := typed.AddTarget("a", "10.0.0.9", 1); err == nil {
+		t.Error("Expected error when adding duplicate target")
+	}
+	if addr, _ := typed.Target("a"); addr != "10.0.0.1" {
+		t.Errorf("Expected 10.0.0.1, got %s", addr)
+	}
+
+	before, _ := typed.Snapshot().Lookup("key")
+	if err := typed.SetPayload(before, "10.0.0.3"); err != nil {
+		t.Fatalf("SetPayload failed: %v", err)
+	}
+	if addr, _ := typed.Lookup("key"); addr != "10.0.0.3" {
+		t.Errorf("Expected new payload 10.0.0.3, got %s", addr)
+	}
+	if err := typed.SetPayload("c", "10.0.0.4"); err == nil {
+		t.Error("Expected error for non-existent target")
+	}
+
+	typed.RemoveTarget("a")
+	typed.RemoveTarget("b")
+	if _, err := typed.Target("a"); err == nil {
+		t.Error("Expected error for removed target")
+	}
+	if addr, err := typed.Lookup("key"); err == nil || addr != "" {
+		t.Errorf("Expected error and zero payload for empty ring, got %q, %v", addr, err)
+	}
+	if err := typed.RemoveTarget("a"); err == nil {
+		t.Error("Expected error when removing non-existent target")
+	}
+}
+
+// Run with -race to check that lookups see consistent payloads
+func TestTypedFlexiHashParallel(t *testing.T) {
+	typed := NewTypedFlexiHash[*testBackend]()
+	typed.AddTarget("stable", &testBackend{name: "stable"}, 1)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				backend, err := typed.Lookup("key-" + strconv.Itoa(i))
+				if err != nil || backend == nil {
+					t.Errorf("Expected a payload, got %v (%v)", backend, err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		name := "extra-" + strconv.Itoa(i%3)
+		typed.AddTarget(name, &testBackend{name: name}, 1)
+		typed.RemoveTarget(name)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,69 @@
+package flexihash
+
+import "errors"
+
+// SetTargetZone labels a target with its failure domain, such as a rack or
+// availability zone. An empty zone removes the label; unlabelled targets
+// count as a failure domain of their own.
+func (fh *FlexiHash) SetTargetZone(target, zone string) error {
+	if fh.frozen {
+		return errors.New("Ring is frozen")
+	}
+	if _, exists := fh.targetToPositions[target]; !exists {
+		return errors.New("Target '" + target + "' does not exist.")
+	}
+	if zone == "" {
+		delete(fh.targetZone, target)
+	} else {
+		fh.targetZone[target] = zone
+	}
+	return nil
+}
+
+// Zone returns the failure domain label of a target, or "" if it has none
+func (fh *FlexiHash) Zone(target string) (string, error) {
+	if _, exists := fh.targetToPositions[target]; !exists {
+		return "", errors.New("Target '" + target + "' does not exist.")
+	}
+	return fh.targetZone[target], nil
+}
+
+// LookupListDistinct returns targets for the resource like LookupList, but
+// places them in distinct failure domains where possible. Walking clockwise,
+// a target whose zone already holds a result is passed over; if there are
+// fewer zones than requested targets, the passed-over targets fill the
+// remaining places in clockwise order.
+func (fh *FlexiHash) LookupListDistinct(resource string, requestedCount int) ([]string, error) {
+	if requestedCount < 1 {
+		return nil, errors.New("Invalid count requested")
+	}
+	fh.sortPositionTargets()
+	if fh.positionCount == 0 {
+		return []string{}, nil
+	}
+
+	var results, passedOver []string
+	usedZones := make(map[string]bool)
+	fh.walk(fh.lookupPosition(resource), func(target string) bool {
+		if !fh.isUp(target) {
+			return true
+		}
+		if zone, labelled := fh.targetZone[target]; labelled {
+			if usedZones[zone] {
+				passedOver = append(passedOver, target)
+				return true
+			}
+			usedZones[zone] = true
+		}
+		results = append(results, target)
+		return len(results) < requestedCount
+	})
+
+	for _, target := range passedOver {
+		if len(results) == requestedCount {
+			break
+		}
+		results = append(results, target)
+	}
+	return results, nil
+}
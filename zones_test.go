@@ -0,0 +1,139 @@
+package flexihash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func zonedRing() *FlexiHash {
+	fh := NewFlexiHash()
+	for _, zone := range []string{"a", "b", "c"} {
+		for i := 1; i <= 3; i++ {
+			target := zone + strconv.Itoa(i)
+			fh.AddTarget(target, 1)
+			fh.SetTargetZone(target, zone)
+		}
+	}
+	return fh
+}
+
+func TestLookupListDistinctUsesDistinctZones(t *testing.T) {
+	fh := zonedRing()
+	for i := 0; i < 500; i++ {
+		key := "key-" + strconv.Itoa(i)
+		targets, err := fh.LookupListDistinct(key, 3)
+		if err != nil {
+			t.Fatalf("LookupListDistinct failed: %v", err)
+		}
+		zones := make(map[string]bool)
+		for _, target := range targets {
+			zone, _ := fh.Zone(target)
+			zones[zone] = true
+		}
+		if len(targets) != 3 || len(zones) != 3 {
+			t.Fatalf("Key %s: expected 3 targets in 3 zones, got %v", key, targets)
+		}
+
+		// The primary is unchanged
+		primary, _ := fh.Lookup(key)
+		if targets[0] != primary {
+			t.Fatalf("Key %s: expected primary %s, got %s", key, primary, targets[0])
+		}
+	}
+}
+
+func TestLookupListDistinctFallsBack(t *testing.T) {
+	fh := zonedRing()
+	for i := 0; i < 200; i++ {
+		key := "key-" + strconv.Itoa(i)
+		targets, _ := fh.LookupListDistinct(key, 5)
+		if len(targets) != 5 {
+			t.Fatalf("Key %s: expected 5 targets, got %v", key, targets)
+		}
+
+		// The first three cover every zone, the rest follow clockwise order
+		zones := make(map[string]bool)
+		for _, target := range targets[:3] {
+			zone, _ := fh.Zone(target)
+			zones[zone] = true
+		}
+		if len(zones) != 3 {
+			t.Fatalf("Key %s: expected the first 3 targets in 3 zones, got %v", key, targets)
+		}
+		order, _ := fh.LookupList(key, 9)
+		var rest []string
+		for _, target := range order {
+			if target != targets[0] && target != targets[1] && target != targets[2] {
+				rest = append(rest, target)
+			}
+		}
+		if targets[3] != rest[0] || targets[4] != rest[1] {
+			t.Fatalf("Key %s: expected fallback %v, got %v", key, rest[:2], targets[3:])
+		}
+	}
+}
+
+func TestLookupListDistinctUnlabelledTargets(t *testing.T) {
+	fh := NewFlexiHash()
+	fh.AddTargets([]string{"t1", "t2", "t3"}, 1)
+	for i := 0; i < 200; i++ {
+		key := "key-" + strconv.Itoa(i)
+		expected, _ := fh.LookupList(key, 3)
+		got, _ := fh.LookupListDistinct(key, 3)
+		if len(got) != 3 || got[0] != expected[0] || got[1] != expected[1] || got[2] != expected[2] {
+			t.Fatalf("Key %s: expected %v, got %v", key, expected, got)
+		}
+	}
+
+	if _, err := fh.LookupListDistinct("key", 0); err == nil {
+		t.Error("Expected error for invalid count")
+	}
+	if err := fh.SetTargetZone("t4", "a"); err == nil {
+		t.Error("Expected error for non-existent target")
+	}
+}
+
+func TestLookupListDistinctSkipsDownTargets(t *testing.T) {
+	cfh := NewConcurrentFlexiHashFrom(zonedRing())
+	cfh.SetTargetStatus("a1", StatusDown)
+	cfh.SetTargetStatus("a2", StatusDown)
+	for i := 0; i < 200; i++ {
+		targets, _ := cfh.LookupListDistinct("key-"+strconv.Itoa(i), 3)
+		for _, target := range targets {
+			if target == "a1" || target == "a2" {
+				t.Fatalf("Expected down targets to be skipped, got %v", targets)
+			}
+		}
+		if len(targets) != 3 || targets[0][0] == targets[1][0] || targets[1][0] == targets[2][0] || targets[0][0] == targets[2][0] {
+			t.Fatalf("Expected 3 targets in 3 zones, got %v", targets)
+		}
+	}
+}
+
+func TestZonesRoundTrip(t *testing.T) {
+	fh := zonedRing()
+	fh.SetTargetZone("c3", "")
+
+	data, err := fh.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	restored := NewFlexiHash()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	for _, target := range fh.GetAllTargets() {
+		expected, _ := fh.Zone(target)
+		if got, _ := restored.Zone(target); got != expected {
+			t.Errorf("Target %s: expected zone %q, got %q", target, expected, got)
+		}
+	}
+
+	// Zones change the fingerprint, since they change placement
+	before, _ := fh.Fingerprint()
+	fh.SetTargetZone("c3", "c")
+	after, _ := fh.Fingerprint()
+	if before == after {
+		t.Error("Expected zone change to change the fingerprint")
+	}
+}